@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/cache"
+	"github.com/dmitrymomot/go-app-template/db/outbox"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	"github.com/dmitrymomot/go-app-template/internal/config"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// dbRouter is the database router with the read replica connections it owns.
+type dbRouter struct {
+	*db.Router
+	replicas []*sql.DB
+}
+
+// initDBRouter opens the read replicas and creates the router over them and the primary connection.
+func initDBRouter(ctx context.Context, cfg config.DB, primary *sql.DB, log *zap.SugaredLogger) (*dbRouter, error) {
+	replicas := make([]*sql.DB, 0, len(cfg.ReadReplicaURLs))
+	for _, u := range cfg.ReadReplicaURLs {
+		conn, _, err := db.Open(ctx, u, db.Options{
+			MaxOpenConns:    cfg.MaxOpenConns,
+			MaxIdleConns:    cfg.MaxIdleConns,
+			ConnMaxLifetime: cfg.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+			ConnectTimeout:  cfg.ConnectTimeout,
+		})
+		if err != nil {
+			for _, r := range replicas {
+				_ = r.Close()
+			}
+			return nil, errtrace.Wrap(err)
+		}
+		replicas = append(replicas, conn)
+	}
+
+	router := db.NewRouter(primary, replicas, db.RouterOptions{
+		OnReplicaStateChange: func(index int, healthy bool, err error) {
+			if healthy {
+				log.Infow("Read replica recovered", "replica", index)
+				return
+			}
+			log.Warnw("Read replica is unhealthy, reads are sent to other replicas", "replica", index, "error", err)
+		},
+	})
+
+	return &dbRouter{Router: router, replicas: replicas}, nil
+}
+
+// Close stops the router and closes the replica connections.
+func (r *dbRouter) Close() {
+	r.Router.Close()
+	for _, conn := range r.replicas {
+		_ = conn.Close()
+	}
+}
+
+// readYourWrites is a middleware which sends the reads of the request to the primary database
+// after the request made its first write, so handlers see their own changes despite replication lag.
+func readYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(db.WithReadYourWrites(r.Context())))
+	})
+}
+
+// initQueryCache wraps the repository with the read-through cache, Redis failures are logged.
+func initQueryCache(repo repository.Querier, rdb redis.UniversalClient, cfg config.QueryCache, log *zap.SugaredLogger) *cache.Querier {
+	localSize := cfg.LocalSize
+	if localSize == 0 {
+		localSize = -1 // disabled
+	}
+	return cache.New(repo, rdb, cache.Options{
+		Prefix:    cfg.Prefix,
+		LocalSize: localSize,
+		LocalTTL:  cfg.LocalTTL,
+		OnError: func(err error) {
+			log.Warnw("Query cache is unavailable, falling back to the database", "error", err)
+		},
+	})
+}
+
+// initOutboxRelay creates the relay publishing the outbox tasks to the queue, failures are logged.
+// It reads the outbox on the primary database, the replicas may not have the committed messages yet.
+func initOutboxRelay(primary *sql.DB, dialect db.Dialect, client *asynq.Client, cfg config.Outbox, log *zap.SugaredLogger) (*outbox.Relay, error) {
+	pub, err := outbox.NewAsynqPublisher(client, outbox.PublisherOptions{})
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	return errtrace.Wrap2(outbox.NewRelay(db.NewQuerier(dialect, primary), pub, outbox.Options{
+		PollInterval: cfg.PollInterval,
+		BatchSize:    cfg.BatchSize,
+		MaxBackoff:   cfg.MaxBackoff,
+		CleanupAfter: cfg.Retention,
+		OnError: func(err error) {
+			log.Warnw("Failed to relay outbox messages, they will be retried", "error", err)
+		},
+	}))
+}
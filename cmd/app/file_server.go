@@ -12,8 +12,9 @@ import (
 )
 
 // serveFile is a helper function to serve a single file and handle caching.
+// Zero cacheTTL disables caching.
 func serveFile(w http.ResponseWriter, r *http.Request, file http.File, info os.FileInfo, cacheTTL time.Duration) {
-	if cacheTTL == 0 || appDebugMode {
+	if cacheTTL == 0 {
 		// No caching
 		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
 		return
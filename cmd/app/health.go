@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dmitrymomot/go-app-template/pkg/render"
+)
+
+// readinessTimeout limits each dependency check of the readiness endpoint.
+const readinessTimeout = 2 * time.Second
+
+// healthProbe checks that a dependency is reachable.
+type healthProbe func(ctx context.Context) error
+
+// readiness is a middleware which responds on the given path with the state of the dependencies:
+// 200 if all probes pass and 503 otherwise. Like middleware.Heartbeat, it must be placed
+// before rate limiting and sessions, so orchestrators can poll it freely.
+// Error details are not exposed, only the names of the failed dependencies.
+func readiness(path string, probes map[string]healthProbe) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || !strings.EqualFold(r.URL.Path, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			status := http.StatusOK
+			result := make(map[string]string, len(probes))
+			for name, probe := range probes {
+				result[name] = "ok"
+				if err := probe(r.Context()); err != nil {
+					result[name] = "unavailable"
+					status = http.StatusServiceUnavailable
+				}
+			}
+
+			w.Header().Set(render.ContentTypeHeader, render.ContentTypeJSONUTF)
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(result)
+		})
+	}
+}
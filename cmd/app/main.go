@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"expvar"
 	"fmt"
 	stdLog "log"
 	"net/http"
@@ -9,11 +11,22 @@ import (
 
 	"braces.dev/errtrace"
 	"github.com/dmitrymomot/asyncer"
-	libsql_remote "github.com/dmitrymomot/go-app-template/db/libsql/remote"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/cache"
+	"github.com/dmitrymomot/go-app-template/db/instrument"
+	libsql_embeded "github.com/dmitrymomot/go-app-template/db/libsql/embeded"
+	"github.com/dmitrymomot/go-app-template/db/outbox"
+	"github.com/dmitrymomot/go-app-template/internal/config"
+	"github.com/dmitrymomot/go-app-template/pkg/binding"
+	"github.com/dmitrymomot/go-app-template/pkg/render"
+	"github.com/dmitrymomot/go-app-template/web/templates/views"
 	"github.com/dmitrymomot/httpserver"
 	"github.com/dmitrymomot/mailer"
 	"github.com/dmitrymomot/mailer/adapters/postmark"
+	_ "github.com/joho/godotenv/autoload" // Load .env file automatically
+	_ "github.com/lib/pq"                 // init postgres driver
 	"github.com/redis/go-redis/v9"
+	_ "github.com/tursodatabase/go-libsql" // init libSQL driver
 	"golang.org/x/sync/errgroup"
 )
 
@@ -22,8 +35,19 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Load and validate config
+	cfg, err := config.Load()
+	if err != nil {
+		stdLog.Fatal(err)
+	}
+
+	// Refuse to boot in production-like environments with insecure defaults
+	if err := cfg.CheckSafety(); err != nil {
+		stdLog.Fatal(err)
+	}
+
 	// Init logger with default fields
-	log := initLogger()
+	log := initLogger(cfg.App)
 	defer func() {
 		if err := log.Sync(); err != nil {
 			stdLog.Printf("Failed to flush log buffer: %v", err)
@@ -37,28 +61,106 @@ func main() {
 	defer func() { logger.Info("Server successfully shutdown") }()
 
 	// Init db connection
-	db, err := libsql_remote.Connect(dbConnString, dbMaxOpenConns, dbMaxIdleConns)
+	var dbConn *sql.DB
+	dialect := db.DialectSQLite
+	if cfg.DB.ReplicaPath != "" {
+		// Embedded replica: reads are served from the local file, writes go to the primary
+		replica, err := libsql_embeded.Connect(libsql_embeded.Config{
+			Path:         cfg.DB.ReplicaPath,
+			PrimaryURL:   cfg.DB.URL,
+			SyncInterval: cfg.DB.SyncInterval,
+			Logger:       logger.With("component", "db_replica"),
+		})
+		if err != nil {
+			mainLogger.Fatalw("Failed to open embedded replica", "error", err)
+		}
+		defer replica.Close()
+		dbConn = replica.DB()
+	} else {
+		// The driver is chosen by the DATABASE_URL scheme
+		// The connection is retried while the database is starting up.
+		dbLogger := logger.With("component", "db")
+		dbConn, dialect, err = db.Open(ctx, cfg.DB.URL, db.Options{
+			MaxOpenConns:    cfg.DB.MaxOpenConns,
+			MaxIdleConns:    cfg.DB.MaxIdleConns,
+			ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime,
+			ConnectTimeout:  cfg.DB.ConnectTimeout,
+			OnConnectRetry: func(attempt int, err error, delay time.Duration) {
+				dbLogger.Warnw("Database is not reachable, retrying", "attempt", attempt, "delay", delay, "error", err)
+			},
+		})
+		if err != nil {
+			mainLogger.Fatalw("Failed to open db connection", "error", err)
+		}
+		defer dbConn.Close()
+	}
+
+	// Reads are sent to the read replicas, if any, everything else to the primary.
+	// Transactions must be run on dbRouter.Primary().
+	dbRouter, err := initDBRouter(ctx, cfg.DB, dbConn, logger.With("component", "db_router"))
 	if err != nil {
-		mainLogger.Fatalw("Failed to open remote db connection", "error", err)
+		mainLogger.Fatalw("Failed to open read replicas", "error", err)
+	}
+	defer dbRouter.Close()
+
+	// Slow queries are logged and the latencies are published at /debug/vars of the metrics server.
+	dbMetrics := instrument.NewMetrics()
+	expvar.Publish("db_queries", dbMetrics)
+	repo := db.NewQuerier(dialect, instrument.Wrap(dbRouter, instrument.Options{
+		SlowThreshold: cfg.DB.SlowQueryThreshold,
+		Metrics:       dbMetrics,
+		Logger:        logger.With("component", "db"),
+	}))
+
+	// Apply pending migrations, if enabled.
+	// The lock makes sure only one instance runs migrations at a time.
+	if cfg.Migrations.AutoMigrate {
+		n, err := autoMigrate(ctx, dbConn, dialect, cfg.Migrations)
+		if err != nil {
+			mainLogger.Fatalw("Failed to apply migrations", "error", err)
+		}
+		mainLogger.Infow("Migrations applied", "count", n)
 	}
-	defer db.Close()
 
 	// Init redis connection
-	redisConnOpt, err := redis.ParseURL(redisConnString)
+	redisConnOpt, err := redis.ParseURL(cfg.Redis.URL)
 	if err != nil {
 		mainLogger.Fatalw("Failed to parse redis connection string", "error", err)
 	}
 	redisClient := redis.NewClient(redisConnOpt)
 	defer redisClient.Close()
 
-	// Create a new enqueuer with redis as the broker.
-	enqueuer := asyncer.MustNewEnqueuer(redisConnString)
-	defer enqueuer.Close()
+	// Cache the repository reads in the in-process LRU and Redis.
+	// Writes made through the repository invalidate the cached data on all instances.
+	// Transactions must pass queryCache.WithTx as db.TxOptions.WrapQuerier for their writes to do the same.
+	var queryCache *cache.Querier
+	if cfg.QueryCache.Enabled {
+		queryCache = initQueryCache(repo, redisClient, cfg.QueryCache, logger.With("component", "query_cache"))
+		repo = queryCache
+	}
+	_ = repo // TODO: remove this line and pass the repository to the handlers.
+
+	// Tasks are written to the outbox in the transaction of the business change
+	// and relayed to the queue with redis as the broker, so they are not lost if redis is down after the commit.
+	queueClient, _, err := asyncer.NewClient(cfg.Redis.URL)
+	if err != nil {
+		mainLogger.Fatalw("Failed to create queue client", "error", err)
+	}
+	defer queueClient.Close()
+	outboxRelay, err := initOutboxRelay(dbRouter.Primary(), dialect, queueClient, cfg.Outbox, logger.With("component", "outbox"))
+	if err != nil {
+		mainLogger.Fatalw("Failed to create outbox relay", "error", err)
+	}
+	enqueuer, err := outbox.NewEnqueuer(dbRouter.Primary(), dialect)
+	if err != nil {
+		mainLogger.Fatalw("Failed to create outbox enqueuer", "error", err)
+	}
 
 	// Create a new email provider client.
-	postmarkAdapter, err := postmark.New(postmarkServerToken, postmarkAccountToken, postmark.Config{
-		From:       emailFrom,
-		ReplyTo:    emailReplyTo,
+	postmarkAdapter, err := postmark.New(cfg.Postmark.ServerToken, cfg.Postmark.AccountToken, postmark.Config{
+		From:       cfg.Email.From,
+		ReplyTo:    cfg.Email.ReplyTo,
 		TrackOpens: true,
 		TrackLinks: true,
 	})
@@ -68,34 +170,68 @@ func main() {
 	_ = postmarkAdapter
 
 	// Create a new mail enqueuer.
+	// Emails sent with the context of db.RunInTx are enqueued only if the transaction is committed.
 	mailEnqueuer := mailer.NewEnqueuer(enqueuer)
 	_ = mailEnqueuer // TODO: remove this line and use the mailEnqueuer to send emails via the queue.
 
+	// Init session manager, it's shared by the router and the form binder
+	sessionManager := initSessionManager(cfg.Session, redisClient)
+
+	// Decode and validate the request bodies, the forms are flashed through the session on errors.
+	binder := binding.New(binding.Options{
+		BodyLimit: cfg.HTTP.BodyLimit,
+		Session:   sessionManager,
+	})
+	_ = binder // TODO: remove this line and pass the binder to the handlers.
+
 	// Init router
-	r := initRouter(logger, redisClient)
+	r := initRouter(cfg, logger, redisClient, sessionManager, db.HealthProbe(dbConn, readinessTimeout))
 
 	// TODO: remove this route and add your own instead.
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("Hello, World!"))
+		render.Render(w, r, views.HomePage(), http.StatusOK)
 	})
 
 	eg, ctx := errgroup.WithContext(ctx)
 
 	// Run server
 	eg.Go(func() error {
-		server := httpserver.New(fmt.Sprintf(":%d", httpPort), r,
-			httpserver.WithReadTimeout(httpReadTimeout),
-			httpserver.WithWriteTimeout(httpWriteTimeout),
+		server := httpserver.New(fmt.Sprintf(":%d", cfg.HTTP.Port), r,
+			httpserver.WithReadTimeout(cfg.HTTP.ReadTimeout),
+			httpserver.WithWriteTimeout(cfg.HTTP.WriteTimeout),
 			httpserver.WithGracefulShutdown(10*time.Second),
 		)
 		return errtrace.Wrap(server.Start(ctx))
 	})
 
+	// Run the internal metrics server, it doesn't depend on the debug mode,
+	// so the metrics are available in production
+	if cfg.HTTP.MetricsPort > 0 {
+		eg.Go(func() error {
+			server := httpserver.New(fmt.Sprintf(":%d", cfg.HTTP.MetricsPort), metricsHandler(),
+				httpserver.WithReadTimeout(cfg.HTTP.ReadTimeout),
+				httpserver.WithWriteTimeout(cfg.HTTP.WriteTimeout),
+				httpserver.WithGracefulShutdown(10*time.Second),
+			)
+			return errtrace.Wrap(server.Start(ctx))
+		})
+	}
+
+	// Evict the keys invalidated by the other instances from the in-process cache
+	if queryCache != nil {
+		eg.Go(func() error {
+			return errtrace.Wrap(queryCache.Subscribe(ctx))
+		})
+	}
+
+	// Publish the pending outbox tasks to the queue
+	eg.Go(func() error {
+		return errtrace.Wrap(outboxRelay.Run(ctx))
+	})
+
 	// Run a new queue server with redis as the broker.
 	eg.Go(asyncer.RunQueueServer(
-		ctx, redisConnString, logger,
+		ctx, cfg.Redis.URL, logger,
 		// Register the task handlers.
 		mailer.SendEmailHandler(postmarkAdapter), // Register the send_email task handler.
 		// ... add more handlers here ...
@@ -104,7 +240,7 @@ func main() {
 	// Run a scheduler with redis as the broker.
 	// The scheduler will schedule tasks to be enqueued at a specified time.
 	eg.Go(asyncer.RunSchedulerServer(
-		ctx, redisConnString, logger,
+		ctx, cfg.Redis.URL, logger,
 		// Schedule the scheduled_task task to be enqueued every 1 seconds.
 		// asyncer.NewTaskScheduler("@every 1s", TestTaskName),
 		// ... add more scheduled tasks here ...
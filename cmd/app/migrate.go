@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/migration"
+	"github.com/dmitrymomot/go-app-template/db/sql/migrations"
+	"github.com/dmitrymomot/go-app-template/internal/config"
+)
+
+// autoMigrate applies all pending migrations holding the cross-instance migrations lock.
+// It waits for the lock no longer than cfg.LockTimeout.
+// It returns the number of applied migrations.
+func autoMigrate(ctx context.Context, conn *sql.DB, dialect db.Dialect, cfg config.Migrations) (int, error) {
+	m, err := migration.NewMigratorFS(conn, string(dialect), cfg.Table, migrations.Source(string(dialect), cfg.Dir))
+	if err != nil {
+		return 0, errtrace.Wrap(err)
+	}
+
+	return errtrace.Wrap2(m.UpWithLock(ctx, cfg.LockTimeout))
+}
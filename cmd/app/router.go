@@ -1,16 +1,21 @@
 package main
 
 import (
-	"encoding/json"
-	"errors"
+	"expvar"
+	"fmt"
 	"net/http"
 	"os"
-	"strings"
 
+	"github.com/a-h/templ"
 	"github.com/alexedwards/scs/goredisstore"
 	"github.com/alexedwards/scs/v2"
 	"github.com/dmitrymomot/clientip"
+	"github.com/dmitrymomot/go-app-template/db/instrument"
+	"github.com/dmitrymomot/go-app-template/internal/config"
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
 	"github.com/dmitrymomot/go-app-template/pkg/logger"
+	"github.com/dmitrymomot/go-app-template/pkg/negotiate"
+	"github.com/dmitrymomot/go-app-template/pkg/recovery"
 	"github.com/dmitrymomot/go-app-template/web/templates/views"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -22,45 +27,74 @@ import (
 	"go.uber.org/zap"
 )
 
+// httpPanics counts the recovered panics at /debug/vars. It's published once per process,
+// as expvar.Publish panics on a duplicate name.
+var httpPanics = expvar.NewInt("http_panics")
+
 // initRouter initializes and configures the router for the application.
 // It sets up the middleware stack, handles CORS, disables caching in debug mode,
 // and registers default error handlers. It also handles serving static files
 // from the './web/static' subdirectory.
-func initRouter(log *zap.SugaredLogger, redisClient *redis.Client) *chi.Mux {
+func initRouter(cfg config.Config, log *zap.SugaredLogger, redisClient *redis.Client, sessionManager *scs.SessionManager, dbProbe healthProbe) *chi.Mux {
 	r := chi.NewRouter()
 
+	// Errors are sent as problem+json to JSON clients, as a toast to htmx requests
+	// and as the error page to everyone else. Unexpected errors are logged with their trace.
+	httperr.SetDefault(httperr.NewRenderer(httperr.Options{
+		Logger:      log.With("component", "http"),
+		Page:        views.ErrorPage,
+		Fragment:    views.ErrorToast,
+		ToastTarget: "#" + views.ToastsID,
+	}))
+
+	// N+1 query detection is only enabled in debug mode
+	nPlusOneThreshold := 0
+	if cfg.App.DebugMode {
+		nPlusOneThreshold = cfg.DB.NPlusOneThreshold
+	}
+
 	// Middleware stack
 	r.Use(
 		middleware.Heartbeat("/health"),
-		middleware.ThrottleBacklog(httpTrottleLimit, httpTrottleBacklog, httpTrottleTimeout),
+		readiness("/ready", map[string]healthProbe{"db": dbProbe}),
+		middleware.ThrottleBacklog(cfg.HTTP.ThrottleLimit, cfg.HTTP.ThrottleBacklog, cfg.HTTP.ThrottleTimeout),
 		clientip.Middleware(),
-		httprate.LimitByRealIP(httpRequestLimit, httpRateLimitWindow), // Limit requests per IP
+		middleware.RequestID, // Request ID for the logs, e.g. of the recovered panics
+		httprate.LimitByRealIP(cfg.HTTP.RequestLimit, cfg.HTTP.RateLimitWindow), // Limit requests per IP
 		httprate.Limit(
-			httpRequestLimit,
-			httpRateLimitWindow,
+			cfg.HTTP.RequestLimit,
+			cfg.HTTP.RateLimitWindow,
 			httprate.WithKeyByIP(),
 			httprateredis.WithRedisLimitCounter(&httprateredis.Config{
 				Client: redisClient,
 			}),
 		),
 		logger.LogRequest(log),
-		middleware.Recoverer,
+		readYourWrites,
+		instrument.Middleware(log.With("component", "db"), nPlusOneThreshold),
+		recovery.Middleware(recovery.Options{
+			Logger:    log.With("component", "http"),
+			Debug:     cfg.App.DebugMode,
+			DebugPage: panicPage,
+			Panics:    httpPanics,
+		}),
 		middleware.CleanPath,
 		middleware.StripSlashes,
 		middleware.GetHead,
-		middleware.Timeout(httpRequestTimeout),
+		middleware.Timeout(cfg.HTTP.RequestTimeout),
+		middleware.RequestSize(cfg.HTTP.BodyLimit),                // Limit the request body size
 		middleware.SetHeader("X-Content-Type-Options", "nosniff"), // Protection against MIME-sniffing
 		middleware.SetHeader("X-Frame-Options", "deny"),           // Protection against clickjacking
-		middleware.SetHeader("Server", serverHeader),
+		middleware.SetHeader("Server", cfg.HTTP.ServerHeader),
 
 		// Basic CORS
 		// for more ideas, see: https://developer.github.com/v3/#cross-origin-resource-sharing
 		cors.Handler(cors.Options{
-			AllowedOrigins:   corsAllowedOrigins,
-			AllowedMethods:   corsAllowedMethods,
-			AllowedHeaders:   corsAllowedHeaders,
-			AllowCredentials: corsAllowedCredentials,
-			MaxAge:           corsMaxAge, // Maximum value not ignored by any of major browsers
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge, // Maximum value not ignored by any of major browsers
 		}),
 
 		// TODO: route headers, useful for setting different routers for subdomains
@@ -69,47 +103,44 @@ func initRouter(log *zap.SugaredLogger, redisClient *redis.Client) *chi.Mux {
 
 		// CSRF protection
 		// For more details, see https://github.com/gorilla/csrf?tab=readme-ov-file#html-forms
-		csrf.Protect(csrfSecret,
+		csrf.Protect(cfg.CSRF.Secret,
 			csrf.RequestHeader("X-CSRF-Token"),
 			csrf.CookieName("X-CSRF-Token"),
 			csrf.FieldName("_csrf"),
 			csrf.SameSite(csrf.SameSiteLaxMode),
-			csrf.Secure(appEnv == "production"),
-			csrf.TrustedOrigins(corsAllowedOrigins), // Allow cross-domain CSRF use-cases
+			csrf.Secure(cfg.Session.CookieSecure),
+			csrf.TrustedOrigins(cfg.CORS.AllowedOrigins), // Allow cross-domain CSRF use-cases
 			csrf.ErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				sendErrorResponse(w, r, http.StatusForbidden, errors.New("CSRF token invalid"))
+				httperr.Render(w, r, httperr.ErrForbidden.WithMessage("CSRF token invalid").WithCause(csrf.FailureReason(r)))
 			})),
 		),
 	)
 
 	// Disable caching
-	if disableHTTPCache {
+	if cfg.HTTP.DisableCache {
 		r.Use(middleware.NoCache)
 	}
 
-	// Initialize a new session manager and configure the session lifetime.
-	sessionManager := scs.New()
-	sessionManager.Lifetime = sessionTTL
-	sessionManager.Cookie.Name = sessionName
-	sessionManager.Cookie.Secure = appEnv == EnvProduction
-	sessionManager.Cookie.Persist = true
-	sessionManager.Cookie.SameSite = http.SameSiteLaxMode
-	sessionManager.Cookie.HttpOnly = true
-	sessionManager.Store = goredisstore.NewWithPrefix(redisClient, sessionPrefix)
+	// Load and save the session data of each request
 	r.Use(sessionManager.LoadAndSave)
 
 	// Default error handlers
 	r.NotFound(notFoundHandler())
 	r.MethodNotAllowed(methodNotAllowedHandler())
 
-	if appDebugMode {
+	if cfg.App.DebugMode {
 		// Profiler endpoints, only for debug mode
 		r.Mount("/debug", middleware.Profiler())
 	}
 
 	// Static file serving from '/assets' subdirectory without directory listing.
-	if _, err := os.Stat(staticDir); !os.IsNotExist(err) {
-		if err := fileServer(r, staticURLPrefix, http.Dir(staticDir), staticCacheTTL); err != nil {
+	// Caching is disabled in debug mode.
+	if _, err := os.Stat(cfg.Static.Dir); !os.IsNotExist(err) {
+		cacheTTL := cfg.Static.CacheTTL
+		if cfg.App.DebugMode {
+			cacheTTL = 0
+		}
+		if err := fileServer(r, cfg.Static.URLPrefix, http.Dir(cfg.Static.Dir), cacheTTL); err != nil {
 			log.Fatal(err)
 		}
 	}
@@ -117,72 +148,45 @@ func initRouter(log *zap.SugaredLogger, redisClient *redis.Client) *chi.Mux {
 	return r
 }
 
+// initSessionManager initializes a new session manager stored in redis and configures the session lifetime.
+func initSessionManager(cfg config.Session, redisClient *redis.Client) *scs.SessionManager {
+	sessionManager := scs.New()
+	sessionManager.Lifetime = cfg.TTL
+	sessionManager.Cookie.Name = cfg.CookieName
+	sessionManager.Cookie.Secure = cfg.CookieSecure
+	sessionManager.Cookie.Persist = true
+	sessionManager.Cookie.SameSite = http.SameSiteLaxMode
+	sessionManager.Cookie.HttpOnly = true
+	sessionManager.Store = goredisstore.NewWithPrefix(redisClient, cfg.Prefix)
+	return sessionManager
+}
+
 // notFoundHandler is a handler for 404 Not Found
 func notFoundHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		err := errors.New("Page not found")
-		if isJsonRequest(r) {
-			err = errors.New("Endpoint not found")
+		err := httperr.ErrNotFound.WithMessage("Page not found")
+		if negotiate.PrefersJSON(r) {
+			err = httperr.ErrNotFound.WithMessage("Endpoint not found")
 		}
-		sendErrorResponse(
-			w, r,
-			http.StatusNotFound,
-			err,
-		)
+		httperr.Render(w, r, err)
 	}
 }
 
 // methodNotAllowedHandler is a handler for 405 Method Not Allowed
 func methodNotAllowedHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sendErrorResponse(
-			w, r,
-			http.StatusMethodNotAllowed,
-			errors.New(http.StatusText(http.StatusMethodNotAllowed)),
-		)
+		httperr.Render(w, r, httperr.ErrMethodNotAllowed)
 	}
 }
 
-// Predefined http encoder content type
-const (
-	contentTypeHeader  = "Content-Type"
-	contextTypeCharset = "charset=utf-8"
-	contentTypeJSON    = "application/json"
-	contentTypeHTML    = "text/html"
-	contentTypeJSONUTF = contentTypeJSON + "; " + contextTypeCharset
-	contentTypeHTMLUTF = contentTypeHTML + "; " + contextTypeCharset
-)
-
-// Helper function to check if an error code is valid
-func isValidErrorCode(errCode int) bool {
-	return errCode >= 400 && errCode < 600
-}
-
-// Is request a json request?
-func isJsonRequest(r *http.Request) bool {
-	return strings.Contains(strings.ToLower(r.Header.Get(contentTypeHeader)), contentTypeJSON)
+// metricsHandler serves the expvar metrics on the internal metrics port.
+func metricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
 }
 
-// Helper function to send an error response
-func sendErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, err error) {
-	if !isValidErrorCode(statusCode) {
-		statusCode = http.StatusInternalServerError
-	}
-
-	if isJsonRequest(r) {
-		w.Header().Set(contentTypeHeader, contentTypeJSONUTF)
-		w.WriteHeader(statusCode)
-		if err := json.NewEncoder(w).Encode(map[string]interface{}{
-			"error": err.Error(),
-		}); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-
-	w.Header().Set(contentTypeHeader, contentTypeHTMLUTF)
-	w.WriteHeader(statusCode)
-	if err := views.ErrorPage(statusCode, err.Error()).Render(r.Context(), w); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+// panicPage shows the panic details in debug mode
+func panicPage(r *http.Request, v any, stack []byte) templ.Component {
+	return views.PanicPage(fmt.Sprint(v), string(stack), r.Method, r.URL.String(), r.Header)
 }
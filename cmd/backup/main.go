@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	stdLog "log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/backup"
+	"github.com/dmitrymomot/go-app-template/db/migration"
+	"github.com/dmitrymomot/go-app-template/db/sql/migrations"
+	"github.com/dmitrymomot/go-app-template/internal/config"
+	_ "github.com/joho/godotenv/autoload"  // Load .env file automatically
+	_ "github.com/lib/pq"                  // init postgres driver
+	_ "github.com/tursodatabase/go-libsql" // init libSQL driver
+	"go.uber.org/zap"
+)
+
+// Exit codes
+const (
+	exitOK    = 0 // command succeeded
+	exitError = 1 // command failed
+	exitUsage = 2 // invalid command or arguments
+)
+
+const usage = `Usage: backup [flags] <command> <path>
+
+Commands:
+  snapshot <file>  Copy the local SQLite database into the file using VACUUM INTO
+  export <dir>     Write the schema and the rows of every table into the directory,
+                   works with any database: SQLite, remote libSQL and Postgres
+  import <dir>     Restore the export into the empty database in a single transaction,
+                   the database may be of another dialect than the source
+
+Flags:
+`
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	// Parse flags
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	dbURL := fs.String("url", "", "Database URL, default: DATABASE_URL")
+	format := fs.String("format", backup.FormatJSONL, "Format of the exported rows: jsonl or csv")
+	migrate := fs.Bool("migrate", false, "Import: apply the migrations before importing, e.g. into a new database")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	args := fs.Args()
+	if len(args) != 2 || !slices.Contains([]string{"snapshot", "export", "import"}, args[0]) {
+		fmt.Fprintln(fs.Output(), "expected a command and a path")
+		fs.Usage()
+		return exitUsage
+	}
+	cmd, path := args[0], args[1]
+
+	// Load config, only the sections required for the database are validated
+	cfg, err := config.Read()
+	if *dbURL != "" {
+		cfg.DB.URL = *dbURL
+	}
+	if err = errors.Join(err, cfg.App.Validate(), cfg.DB.Validate(), cfg.Migrations.Validate()); err != nil {
+		stdLog.Fatal(errors.Join(config.ErrInvalidConfig, err))
+	}
+
+	log := initLogger(cfg.App)
+	defer log.Sync() //nolint:errcheck
+	logger := log.Sugar()
+
+	// VACUUM INTO writes the file on the host running the database,
+	// so the snapshot is limited to the local files
+	if cmd == "snapshot" && !strings.HasPrefix(cfg.DB.URL, "file:") {
+		logger.Errorw("Failed to snapshot database", "error", backup.ErrSnapshotNotSupported)
+		return exitError
+	}
+
+	// Init db connection, the driver is chosen by the DATABASE_URL scheme
+	ctx := context.Background()
+	conn, dialect, err := db.Open(ctx, cfg.DB.URL, db.Options{MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		logger.Errorw("Failed to open database", "error", err)
+		return exitError
+	}
+	defer conn.Close()
+
+	opts := backup.Options{
+		Format:          *format,
+		MigrationsTable: cfg.Migrations.Table,
+		OnProgress:      progressLogger(logger),
+	}
+
+	switch cmd {
+	case "snapshot":
+		if err := backup.Snapshot(ctx, conn, dialect, path); err != nil {
+			logger.Errorw("Failed to snapshot database", "file", path, "error", err)
+			return exitError
+		}
+		logger.Infow("Database snapshot created", "file", path)
+
+	case "export":
+		manifest, err := backup.Export(ctx, conn, dialect, path, opts)
+		if err != nil {
+			logger.Errorw("Failed to export database", "dir", path, "error", err)
+			return exitError
+		}
+		logger.Infow("Database exported", "dir", path, "tables", len(manifest.Tables), "migrations", len(manifest.Migrations))
+
+	case "import":
+		if *migrate {
+			m, err := migration.NewMigratorFS(conn, string(dialect), cfg.Migrations.Table, migrations.Source(string(dialect), cfg.Migrations.Dir))
+			if err == nil {
+				_, err = m.Up(ctx, 0)
+			}
+			if err != nil {
+				logger.Errorw("Failed to apply migrations", "error", err)
+				return exitError
+			}
+		}
+
+		manifest, err := backup.Import(ctx, conn, dialect, path, opts)
+		if err != nil {
+			logger.Errorw("Failed to import database", "dir", path, "error", err)
+			return exitError
+		}
+		logger.Infow("Database imported", "dir", path, "source_dialect", manifest.Dialect, "tables", len(manifest.Tables))
+	}
+
+	return exitOK
+}
+
+// progressLogger returns the progress callback which logs the processed rows.
+func progressLogger(logger *zap.SugaredLogger) func(backup.Progress) {
+	return func(p backup.Progress) {
+		if p.Done {
+			logger.Infow("Table done", "table", p.Table, "rows", p.Rows)
+			return
+		}
+		logger.Infow("Table in progress", "table", p.Table, "rows", p.Rows, "total", p.Total)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dmitrymomot/go-app-template/db/migration"
+	"github.com/dmitrymomot/go-app-template/db/sql/migrations"
+	"github.com/dmitrymomot/go-app-template/internal/config"
+)
+
+// run executes the command and returns the process exit code.
+func (c command) run(ctx context.Context, m *migration.Migrator, out *printer) int {
+	var err error
+	switch c.name {
+	case "status":
+		return c.status(m, out)
+	case "up":
+		err = c.exec(ctx, m, out, migration.DirectionUp)
+	case "down":
+		err = c.exec(ctx, m, out, migration.DirectionDown)
+	case "redo":
+		err = c.redo(ctx, m, out)
+	}
+	if err != nil {
+		out.error(c.name, c.dryRun, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// status prints applied and pending migrations.
+// It returns exitPending if there are migrations to apply.
+func (c command) status(m *migration.Migrator, out *printer) int {
+	list, err := m.Status()
+	if err != nil {
+		out.error(c.name, c.dryRun, err)
+		return exitError
+	}
+
+	pending := 0
+	for _, s := range list {
+		if !s.Applied {
+			pending++
+		}
+	}
+
+	out.status(report{Command: c.name, Status: list, Pending: pending})
+
+	if pending > 0 {
+		return exitPending
+	}
+	return exitOK
+}
+
+// exec applies or rolls back migrations, or prints the plan in dry-run mode.
+func (c command) exec(ctx context.Context, m *migration.Migrator, out *printer, direction string) error {
+	if c.dryRun {
+		plan, err := m.Plan(direction, c.limit)
+		if err != nil {
+			return err
+		}
+		out.plan(report{Command: c.name, DryRun: true, Plan: plan})
+		return nil
+	}
+
+	var n int
+	var err error
+	if direction == migration.DirectionUp {
+		n, err = m.Up(ctx, c.limit)
+	} else {
+		n, err = m.Down(ctx, c.limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	out.done(report{Command: c.name, Count: n})
+	return c.writeSchema(ctx, m, out)
+}
+
+// redo rolls back the last applied migration and applies it again.
+func (c command) redo(ctx context.Context, m *migration.Migrator, out *printer) error {
+	if c.dryRun {
+		plan, err := m.PlanRedo()
+		if err != nil {
+			return err
+		}
+		out.plan(report{Command: c.name, DryRun: true, Plan: plan})
+		return nil
+	}
+
+	id, err := m.Redo(ctx)
+	if err != nil {
+		return err
+	}
+
+	out.done(report{Command: c.name, Count: 1, Migrations: []string{id}})
+	return c.writeSchema(ctx, m, out)
+}
+
+// verify checks that every migration is reversible, replaying them against a scratch database,
+// so the live database is never touched.
+func (c command) verify(ctx context.Context, cfg config.Config, out *printer) int {
+	scratch, err := migration.OpenScratch(ctx, cfg.DB.URL)
+	if err != nil {
+		out.error(c.name, c.dryRun, err)
+		return exitError
+	}
+	defer func() {
+		if err := scratch.Close(context.Background()); err != nil {
+			out.log.Errorw("Failed to close scratch database", "error", err)
+		}
+	}()
+
+	m, err := migration.NewMigratorFS(scratch.DB, scratch.Dialect, cfg.Migrations.Table, migrations.Source(scratch.Dialect, cfg.Migrations.Dir))
+	if err != nil {
+		out.error(c.name, c.dryRun, err)
+		return exitError
+	}
+
+	result, err := m.Verify(ctx)
+	if err != nil && !errors.Is(err, migration.ErrIrreversibleMigration) {
+		out.error(c.name, c.dryRun, err)
+		return exitError
+	}
+
+	r := report{Command: c.name, Count: len(result), Verification: result}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	out.verification(r)
+
+	if err != nil {
+		return exitError
+	}
+	return exitOK
+}
+
+// diff compares the database schema with the migrations applied to it.
+// It returns exitDrift if the schemas differ.
+func (c command) diff(ctx context.Context, m *migration.Migrator, cfg config.Config, out *printer) int {
+	scratch, err := migration.OpenScratch(ctx, cfg.DB.URL)
+	if err != nil {
+		out.error(c.name, c.dryRun, err)
+		return exitError
+	}
+	defer func() {
+		if err := scratch.Close(context.Background()); err != nil {
+			out.log.Errorw("Failed to close scratch database", "error", err)
+		}
+	}()
+
+	drift, err := m.Diff(ctx, scratch.DB)
+	if err != nil {
+		out.error(c.name, c.dryRun, err)
+		return exitError
+	}
+
+	out.diff(report{Command: c.name, Count: drift.Applied, Pending: drift.Pending, Changes: drift.Changes})
+
+	if len(drift.Changes) > 0 {
+		return exitDrift
+	}
+	return exitOK
+}
+
+// writeSchema writes the canonical snapshot of the database schema to the schema file, if it's set.
+func (c command) writeSchema(ctx context.Context, m *migration.Migrator, out *printer) error {
+	if c.schemaFile == "" {
+		return nil
+	}
+
+	schema, err := m.Schema(ctx)
+	if err != nil {
+		return err
+	}
+	list, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	last := "none"
+	for _, s := range list {
+		if s.Applied {
+			last = s.ID
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("-- Code generated by migrate from the database schema. DO NOT EDIT.\n")
+	fmt.Fprintf(&b, "-- Last applied migration: %s\n\n", last)
+	b.WriteString(schema.SQL())
+
+	if err := os.WriteFile(c.schemaFile, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write schema file: %w", err)
+	}
+
+	out.log.Infof("Schema snapshot written to %s", c.schemaFile)
+	return nil
+}
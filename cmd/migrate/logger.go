@@ -3,20 +3,21 @@ package main
 import (
 	stdLog "log"
 
+	"github.com/dmitrymomot/go-app-template/internal/config"
 	"github.com/dmitrymomot/go-app-template/pkg/logger"
 	"go.uber.org/zap"
 )
 
 // init zap logger with default fields
-func initLogger() *zap.Logger {
+func initLogger(cfg config.App) *zap.Logger {
 	log, err := logger.New(logger.Config{
-		AppEnv:    appEnv,
-		Level:     appLogLevel,
-		DebugMode: appDebugMode,
+		AppEnv:    cfg.Env,
+		Level:     cfg.LogLevel,
+		DebugMode: cfg.DebugMode,
 		Fields: map[string]interface{}{
-			"app":       appName,
-			"build_tag": buildTag,
-			"env":       appEnv,
+			"app":       cfg.Name,
+			"build_tag": cfg.BuildTag,
+			"env":       cfg.Env,
 		},
 	})
 	if err != nil {
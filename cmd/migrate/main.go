@@ -1,43 +1,195 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	stdLog "log"
+	"os"
+	"strconv"
 
-	libsql_remote "github.com/dmitrymomot/go-app-template/db/libsql/remote"
+	"github.com/dmitrymomot/go-app-template/db"
 	"github.com/dmitrymomot/go-app-template/db/migration"
+	"github.com/dmitrymomot/go-app-template/db/sql/migrations"
+	"github.com/dmitrymomot/go-app-template/internal/config"
+	_ "github.com/joho/godotenv/autoload"  // Load .env file automatically
+	_ "github.com/lib/pq"                  // init postgres driver
+	_ "github.com/tursodatabase/go-libsql" // init libSQL driver
+	"go.uber.org/zap"
 )
 
+// Exit codes
+const (
+	exitOK      = 0 // command succeeded
+	exitError   = 1 // command failed
+	exitUsage   = 2 // invalid command or arguments
+	exitPending = 3 // status: there are pending migrations
+	exitDrift   = 4 // diff: the database schema differs from the migrations
+)
+
+const usage = `Usage: migrate [flags] <command> [N]
+
+Commands:
+  status    Show applied and pending migrations
+  up [N]    Apply N pending migrations (default: all)
+  down [N]  Roll back N applied migrations (default: 1)
+  redo      Roll back the last applied migration and apply it again
+  verify    Check every migration runs up, down and up again restoring the schema,
+            against a scratch database (in-memory SQLite or a temporary Postgres schema)
+  diff      Compare the database schema with the schema produced by replaying
+            the applied migrations against a scratch database
+
+Running without a command applies all pending migrations.
+
+Flags:
+`
+
+const exitCodesUsage = `
+Exit codes:
+  0  success
+  1  command failed
+  2  invalid command or arguments
+  3  status: there are pending migrations
+  4  diff: the database schema differs from the migrations
+`
+
 func main() {
-	// Parse flags
-	rollback := flag.Bool("rollback", false, "Rollback all migrations")
-	flag.Parse()
+	os.Exit(run())
+}
 
-	log := initLogger()
-	defer log.Sync() //nolint:errcheck
-	logger := log.Sugar()
-	logger.Info("Starting db migration...")
+func run() int {
+	// Parse flags
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Print the SQL that would be executed without running it")
+	jsonOutput := fs.Bool("json", false, "Print the result as JSON")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+		fmt.Fprint(fs.Output(), exitCodesUsage)
+	}
 
-	// Init db connection
-	db, err := libsql_remote.Connect(dbConnString, 1, 1)
+	args, err := parseArgs(fs, os.Args[1:])
 	if err != nil {
-		logger.Fatalw("Failed to open remote db connection", "error", err)
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cmd := command{name: "up", dryRun: *dryRun}
+	if len(args) > 0 {
+		cmd.name = args[0]
+		args = args[1:]
 	}
-	defer db.Close()
+	if err := cmd.parseLimit(args); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return exitUsage
+	}
+
+	out := newPrinter(os.Stdout, *jsonOutput)
 
-	// Rollback all migrations
-	if rollback != nil && *rollback {
-		n, err := migration.Down(db, "sqlite3", migrationsTable, migrationsDir)
-		if err != nil {
-			logger.Fatalw("Failed to rollback migrations", "error", err)
+	// Load config, only the sections required for migrations are validated
+	cfg, err := config.Read()
+	if err = errors.Join(err, cfg.App.Validate(), cfg.DB.Validate(), cfg.Migrations.Validate()); err != nil {
+		if *jsonOutput {
+			out.error(cmd.name, cmd.dryRun, errors.Join(config.ErrInvalidConfig, err))
+			return exitError
 		}
-		logger.Infof("Rolled back %d migrations!", n)
-		return
+		stdLog.Fatal(errors.Join(config.ErrInvalidConfig, err))
 	}
 
-	// Apply all migrations
-	n, err := migration.Up(db, "sqlite3", migrationsTable, migrationsDir)
+	// Logs are suppressed in JSON mode to keep the output machine-readable
+	logger := zap.NewNop().Sugar()
+	if !*jsonOutput {
+		log := initLogger(cfg.App)
+		defer log.Sync() //nolint:errcheck
+		logger = log.Sugar()
+	}
+	out.log = logger
+
+	if cmd.name == "verify" {
+		return cmd.verify(context.Background(), cfg, out)
+	}
+
+	// Init db connection, the driver is chosen by the DATABASE_URL scheme
+	conn, dialect, err := db.Open(context.Background(), cfg.DB.URL, db.Options{MaxOpenConns: 1, MaxIdleConns: 1})
 	if err != nil {
-		logger.Fatalw("Failed to apply migrations", "error", err)
+		out.error(cmd.name, cmd.dryRun, err)
+		return exitError
 	}
-	logger.Infof("Applied %d migrations!", n)
+	defer conn.Close()
+
+	m, err := migration.NewMigratorFS(conn, string(dialect), cfg.Migrations.Table, migrations.Source(string(dialect), cfg.Migrations.Dir))
+	if err != nil {
+		out.error(cmd.name, cmd.dryRun, err)
+		return exitError
+	}
+
+	if cmd.name == "diff" {
+		return cmd.diff(context.Background(), m, cfg, out)
+	}
+
+	cmd.schemaFile = cfg.Migrations.SchemaFile
+	return cmd.run(context.Background(), m, out)
+}
+
+// parseArgs parses flags placed anywhere among the positional arguments
+// and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// command is a parsed migrate command.
+type command struct {
+	name   string
+	limit  int
+	dryRun bool
+	// schemaFile is the path to write the schema snapshot to after migrations are executed,
+	// empty disables the snapshot.
+	schemaFile string
+}
+
+// parseLimit parses the optional N argument of the command.
+func (c *command) parseLimit(args []string) error {
+	switch c.name {
+	case "up":
+		c.limit = 0 // all
+	case "down":
+		c.limit = 1
+	case "status", "redo", "verify", "diff":
+		if len(args) > 0 {
+			return fmt.Errorf("command %q does not accept arguments", c.name)
+		}
+		if (c.name == "verify" || c.name == "diff") && c.dryRun {
+			return fmt.Errorf("command %q does not support dry-run", c.name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", c.name)
+	}
+
+	if len(args) > 1 {
+		return fmt.Errorf("command %q accepts at most one argument", c.name)
+	}
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of migrations %q, must be a positive integer", args[0])
+		}
+		c.limit = n
+	}
+
+	return nil
 }
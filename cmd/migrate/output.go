@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dmitrymomot/go-app-template/db/migration"
+	"go.uber.org/zap"
+)
+
+// report is the result of a command, it is printed as is in JSON mode.
+type report struct {
+	Command      string                   `json:"command"`
+	DryRun       bool                     `json:"dry_run"`
+	Status       []migration.Status       `json:"status,omitempty"`
+	Pending      int                      `json:"pending"`
+	Plan         []migration.Plan         `json:"plan,omitempty"`
+	Count        int                      `json:"count"`
+	Migrations   []string                 `json:"migrations,omitempty"`
+	Verification []migration.Verification `json:"verification,omitempty"`
+	Changes      []migration.Change       `json:"changes,omitempty"`
+	Error        string                   `json:"error,omitempty"`
+}
+
+// printer prints command results either as human-readable text or as JSON.
+type printer struct {
+	w    io.Writer
+	json bool
+	log  *zap.SugaredLogger
+}
+
+// newPrinter creates a new printer which writes to w.
+func newPrinter(w io.Writer, jsonOutput bool) *printer {
+	return &printer{w: w, json: jsonOutput, log: zap.NewNop().Sugar()}
+}
+
+// status prints the list of migrations with their state.
+func (p *printer) status(r report) {
+	if p.json {
+		p.encode(r)
+		return
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MIGRATION\tTYPE\tSTATE\tAPPLIED AT")
+	for _, s := range r.Status {
+		kind := "sql"
+		if s.Go {
+			kind = "go"
+		}
+		state, appliedAt := "pending", "-"
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Missing {
+			state = "applied (missing file)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.ID, kind, state, appliedAt)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintf(p.w, "\n%d applied, %d pending\n", len(r.Status)-r.Pending, r.Pending)
+}
+
+// plan prints the SQL which would be executed.
+func (p *printer) plan(r report) {
+	if p.json {
+		p.encode(r)
+		return
+	}
+
+	if len(r.Plan) == 0 {
+		fmt.Fprintln(p.w, "-- Nothing to do")
+		return
+	}
+	for _, pl := range r.Plan {
+		fmt.Fprintf(p.w, "-- %s: %s\n", strings.ToUpper(pl.Direction), pl.ID)
+		if pl.Go {
+			fmt.Fprintln(p.w, "-- Go migration, the queries are not known in advance")
+		}
+		for _, q := range pl.Queries {
+			fmt.Fprintln(p.w, strings.TrimSpace(q))
+		}
+		fmt.Fprintln(p.w)
+	}
+}
+
+// verification prints the result of the reversibility check of each migration
+// with the schema differences for the failed one.
+func (p *printer) verification(r report) {
+	if p.json {
+		p.encode(r)
+		return
+	}
+
+	for _, v := range r.Verification {
+		if v.OK() {
+			fmt.Fprintf(p.w, "ok    %s\n", v.ID)
+			continue
+		}
+
+		fmt.Fprintf(p.w, "FAIL  %s (%s)\n", v.ID, v.Step)
+		if v.Error != "" {
+			fmt.Fprintf(p.w, "      %s\n", strings.ReplaceAll(v.Error, "\n", "\n      "))
+		}
+		if len(v.Changes) > 0 {
+			expected := "before the migration"
+			if v.Step == migration.StepRedo {
+				expected = "after the first apply"
+			}
+			fmt.Fprintf(p.w, "      schema differs from the one %s (- missing, + extra, ~ changed):\n", expected)
+			for _, c := range v.Changes {
+				fmt.Fprintf(p.w, "        %s\n", c)
+			}
+		}
+	}
+
+	if r.Error != "" {
+		fmt.Fprintf(p.w, "\n%s\n", r.Error)
+		return
+	}
+	fmt.Fprintf(p.w, "\n%d migrations are reversible\n", r.Count)
+}
+
+// diff prints the differences between the database schema and the migrations.
+func (p *printer) diff(r report) {
+	if p.json {
+		p.encode(r)
+		return
+	}
+
+	if r.Pending > 0 {
+		fmt.Fprintf(p.w, "-- %d pending migrations are not taken into account\n", r.Pending)
+	}
+	if len(r.Changes) == 0 {
+		fmt.Fprintf(p.w, "Schema matches %d applied migrations\n", r.Count)
+		return
+	}
+
+	fmt.Fprintf(p.w, "Schema differs from %d applied migrations (- missing, + extra, ~ changed):\n", r.Count)
+	for _, c := range r.Changes {
+		fmt.Fprintf(p.w, "  %s\n", c)
+	}
+}
+
+// done reports the number of executed migrations.
+func (p *printer) done(r report) {
+	if p.json {
+		p.encode(r)
+		return
+	}
+
+	switch r.Command {
+	case "up":
+		p.log.Infof("Applied %d migrations!", r.Count)
+	case "down":
+		p.log.Infof("Rolled back %d migrations!", r.Count)
+	case "redo":
+		p.log.Infof("Reapplied migration %s!", strings.Join(r.Migrations, ", "))
+	}
+}
+
+// error reports the failed command.
+func (p *printer) error(cmd string, dryRun bool, err error) {
+	if p.json {
+		p.encode(report{Command: cmd, DryRun: dryRun, Error: err.Error()})
+		return
+	}
+
+	p.log.Errorw("Failed to run migrations", "command", cmd, "error", err)
+}
+
+// encode writes the report as JSON.
+func (p *printer) encode(r report) {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		p.log.Errorw("Failed to encode output", "error", err)
+	}
+}
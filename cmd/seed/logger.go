@@ -0,0 +1,27 @@
+package main
+
+import (
+	stdLog "log"
+
+	"github.com/dmitrymomot/go-app-template/internal/config"
+	"github.com/dmitrymomot/go-app-template/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// init zap logger with default fields
+func initLogger(cfg config.App) *zap.Logger {
+	log, err := logger.New(logger.Config{
+		AppEnv:    cfg.Env,
+		Level:     cfg.LogLevel,
+		DebugMode: cfg.DebugMode,
+		Fields: map[string]interface{}{
+			"app":       cfg.Name,
+			"build_tag": cfg.BuildTag,
+			"env":       cfg.Env,
+		},
+	})
+	if err != nil {
+		stdLog.Fatal(err)
+	}
+	return log
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	stdLog "log"
+	"os"
+
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/seed"
+	_ "github.com/dmitrymomot/go-app-template/db/seeds" // register Go seeds
+	"github.com/dmitrymomot/go-app-template/internal/config"
+	_ "github.com/joho/godotenv/autoload"  // Load .env file automatically
+	_ "github.com/lib/pq"                  // init postgres driver
+	_ "github.com/tursodatabase/go-libsql" // init libSQL driver
+)
+
+// Exit codes
+const (
+	exitOK    = 0 // seeding succeeded
+	exitError = 1 // seeding failed
+	exitUsage = 2 // invalid arguments
+)
+
+const usage = `Usage: seed [flags]
+
+Loads the fixture files of the environment from <dir>/<env>/*.{yaml,yml,json}
+and runs the registered Go seeds. Existing records are updated, so it can be run many times.
+Refuses to run when APP_ENV or -env is production.
+
+Flags:
+`
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	// Load config, only the sections required for seeding are validated
+	cfg, err := config.Read()
+	if err = errors.Join(err, cfg.App.Validate(), cfg.DB.Validate()); err != nil {
+		stdLog.Fatal(errors.Join(config.ErrInvalidConfig, err))
+	}
+
+	// Parse flags
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	env := fs.String("env", cfg.App.Env, "Environment to seed, selects the fixtures directory")
+	dir := fs.String("dir", "./db/seeds", "Directory with the fixtures of all environments")
+	fakeSeed := fs.Int64("fake-seed", 1, "Seed of the fake values generator, the same seed produces the same values")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %v\n", fs.Args())
+		fs.Usage()
+		return exitUsage
+	}
+
+	log := initLogger(cfg.App)
+	defer log.Sync() //nolint:errcheck
+	logger := log.Sugar()
+
+	// The guard checks both the app environment and the requested one,
+	// so the production database can't be seeded with fixtures of another environment
+	if cfg.App.Env == config.EnvProduction || *env == config.EnvProduction {
+		logger.Errorw("Refusing to seed the database", "app_env", cfg.App.Env, "seed_env", *env, "error", seed.ErrProductionEnv)
+		return exitError
+	}
+
+	fixtures, err := seed.LoadFixtures(os.DirFS(*dir), ".", *env)
+	if err != nil {
+		logger.Errorw("Failed to load fixtures", "dir", *dir, "seed_env", *env, "error", err)
+		return exitError
+	}
+
+	// Init db connection, the driver is chosen by the DATABASE_URL scheme
+	conn, dialect, err := db.Open(context.Background(), cfg.DB.URL, db.Options{MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		logger.Errorw("Failed to open database", "error", err)
+		return exitError
+	}
+	defer conn.Close()
+
+	result, err := seed.Run(context.Background(), conn, fixtures, seed.Options{
+		Env:      *env,
+		Dialect:  dialect,
+		FakeSeed: *fakeSeed,
+	})
+	if err != nil {
+		logger.Errorw("Failed to seed database", "seed_env", *env, "error", err)
+		return exitError
+	}
+
+	for _, r := range result {
+		logger.Infow("Table seeded", "table", r.Table, "inserted", r.Inserted, "updated", r.Updated)
+	}
+	logger.Infow("Database seeded", "seed_env", *env, "fixtures", len(result))
+
+	return exitOK
+}
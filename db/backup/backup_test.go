@@ -0,0 +1,128 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/testdb"
+)
+
+// filesTable has a binary column, which the migrated schema lacks.
+const filesTable = `CREATE TABLE files (id INTEGER PRIMARY KEY, name text NOT NULL, data BLOB)`
+
+func TestExportImport(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			src := openTestDB(t)
+			mustExec(t, src, `INSERT INTO authors (id, name, bio) VALUES (1, 'Rob Pike', NULL)`)
+			mustExec(t, src, `INSERT INTO authors (id, name, bio) VALUES (2, ?, ?)`, `\N`, `\\server\share`)
+			mustExec(t, src, `INSERT INTO authors (id, name, bio) VALUES (3, ?, ?)`, `\x00`, "")
+			mustExec(t, src, `INSERT INTO files (id, name, data) VALUES (1, 'logo', ?)`, []byte{0, 1, '\\', 'N', 0xff})
+			mustExec(t, src, `INSERT INTO files (id, name, data) VALUES (2, 'empty', NULL)`)
+
+			dir := filepath.Join(t.TempDir(), "export")
+			exported, err := Export(ctx, src, db.DialectSQLite, dir, Options{Format: format})
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+
+			dst := openTestDB(t)
+			imported, err := Import(ctx, dst, db.DialectSQLite, dir, Options{})
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if !reflect.DeepEqual(imported.Tables, exported.Tables) {
+				t.Errorf("Import() tables = %v, want %v", imported.Tables, exported.Tables)
+			}
+
+			for _, table := range []string{"authors", "files", "outbox"} {
+				want, got := selectAll(t, src, table), selectAll(t, dst, table)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("table %s after the round trip = %q, want %q", table, got, want)
+				}
+			}
+
+			// The import refuses to overwrite the data
+			if _, err := Import(ctx, dst, db.DialectSQLite, dir, Options{}); !errors.Is(err, ErrDatabaseNotEmpty) {
+				t.Errorf("second Import() error = %v, want %v", err, ErrDatabaseNotEmpty)
+			}
+		})
+	}
+}
+
+func TestExportDirNotEmpty(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	conn := openTestDB(t)
+	dir := t.TempDir()
+	if _, err := Export(ctx, conn, db.DialectSQLite, dir, Options{}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if _, err := Export(ctx, conn, db.DialectSQLite, dir, Options{}); !errors.Is(err, ErrExportDirNotEmpty) {
+		t.Errorf("second Export() error = %v, want %v", err, ErrExportDirNotEmpty)
+	}
+	if _, err := Export(ctx, conn, db.DialectSQLite, t.TempDir(), Options{Format: "xml"}); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Export(xml) error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
+
+// openTestDB returns a migrated database with the files table.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	conn := testdb.Open(t, testdb.Options{})
+	mustExec(t, conn, filesTable)
+	return conn
+}
+
+func mustExec(t *testing.T, conn *sql.DB, query string, args ...any) {
+	t.Helper()
+
+	if _, err := conn.ExecContext(context.Background(), query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// selectAll returns the rows of the table ordered by id, with the values as scanned by the driver.
+func selectAll(t *testing.T, conn *sql.DB, table string) [][]any {
+	t.Helper()
+
+	rows, err := conn.QueryContext(context.Background(), "SELECT * FROM "+quote(table)+" ORDER BY id")
+	if err != nil {
+		t.Fatalf("select %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("select %s: %v", table, err)
+	}
+	var result [][]any
+	for rows.Next() {
+		values := make([]any, len(cols))
+		dest := make([]any, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatalf("select %s: %v", table, err)
+		}
+		result = append(result, values)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("select %s: %v", table, err)
+	}
+	return result
+}
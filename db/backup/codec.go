@@ -0,0 +1,267 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"braces.dev/errtrace"
+)
+
+// Export formats of the table rows.
+const (
+	// FormatJSONL writes a JSON object per row, values keep their types.
+	// Binary values are written as {"$base64": "..."}.
+	FormatJSONL = "jsonl"
+	// FormatCSV writes a header and a line per row, all values are restored as text,
+	// so the target database converts them according to the column types.
+	// NULL is written as \N, binary values as \x followed by hex digits,
+	// and text starting with a backslash gets another backslash.
+	FormatCSV = "csv"
+)
+
+// Special values of the formats.
+const (
+	jsonBinaryKey = "$base64"
+	csvNull       = `\N`
+	csvBinary     = `\x`
+)
+
+// rowWriter writes the rows of a table.
+type rowWriter interface {
+	Write(values []any) error
+	Flush() error
+}
+
+// rowReader reads the rows of a table, it returns io.EOF after the last row.
+type rowReader interface {
+	Read() ([]any, error)
+}
+
+// newRowWriter returns the writer of the format, the CSV header is written immediately.
+func newRowWriter(format string, w io.Writer, columns []string) (rowWriter, error) {
+	switch format {
+	case FormatJSONL:
+		bw := bufio.NewWriter(w)
+		return &jsonlWriter{w: bw, enc: json.NewEncoder(bw), columns: columns}, nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		return &csvWriter{w: cw}, nil
+	}
+	return nil, errtrace.Wrap(fmt.Errorf("%w: %q", ErrInvalidFormat, format))
+}
+
+// newRowReader returns the reader of the format, the values are returned in the order of the columns.
+func newRowReader(format string, r io.Reader, columns []string) (rowReader, error) {
+	switch format {
+	case FormatJSONL:
+		dec := json.NewDecoder(bufio.NewReader(r))
+		dec.UseNumber()
+		return &jsonlReader{dec: dec, columns: columns}, nil
+	case FormatCSV:
+		cr := csv.NewReader(bufio.NewReader(r))
+		cr.FieldsPerRecord = len(columns)
+		cr.ReuseRecord = true
+		header, err := cr.Read()
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("%w: csv header: %w", ErrInvalidExport, err))
+		}
+		for i, col := range columns {
+			if header[i] != col {
+				return nil, errtrace.Wrap(fmt.Errorf("%w: csv header has column %q instead of %q", ErrInvalidExport, header[i], col))
+			}
+		}
+		return &csvReader{r: cr}, nil
+	}
+	return nil, errtrace.Wrap(fmt.Errorf("%w: %q", ErrInvalidFormat, format))
+}
+
+// jsonlWriter writes a JSON object per row.
+type jsonlWriter struct {
+	w       *bufio.Writer
+	enc     *json.Encoder
+	columns []string
+	row     map[string]any
+}
+
+func (j *jsonlWriter) Write(values []any) error {
+	if j.row == nil {
+		j.row = make(map[string]any, len(j.columns))
+	}
+	for i, col := range j.columns {
+		switch v := values[i].(type) {
+		case []byte:
+			j.row[col] = map[string]string{jsonBinaryKey: base64.StdEncoding.EncodeToString(v)}
+		case time.Time:
+			j.row[col] = v.Format(time.RFC3339Nano)
+		default:
+			j.row[col] = v
+		}
+	}
+	return errtrace.Wrap(j.enc.Encode(j.row))
+}
+
+func (j *jsonlWriter) Flush() error {
+	return errtrace.Wrap(j.w.Flush())
+}
+
+// jsonlReader reads a JSON object per row.
+type jsonlReader struct {
+	dec     *json.Decoder
+	columns []string
+}
+
+func (j *jsonlReader) Read() ([]any, error) {
+	var row map[string]any
+	if err := j.dec.Decode(&row); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, errtrace.Wrap(errors.Join(ErrInvalidExport, err))
+	}
+
+	values := make([]any, len(j.columns))
+	for i, col := range j.columns {
+		switch v := row[col].(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				values[i] = f
+			} else {
+				return nil, errtrace.Wrap(fmt.Errorf("%w: column %s: invalid number %q", ErrInvalidExport, col, v))
+			}
+		case map[string]any:
+			s, ok := v[jsonBinaryKey].(string)
+			if !ok {
+				return nil, errtrace.Wrap(fmt.Errorf("%w: column %s: unexpected object", ErrInvalidExport, col))
+			}
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, errtrace.Wrap(fmt.Errorf("%w: column %s: %w", ErrInvalidExport, col, err))
+			}
+			values[i] = b
+		default:
+			values[i] = v
+		}
+	}
+	return values, nil
+}
+
+// csvWriter writes a CSV line per row.
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvWriter) Write(values []any) error {
+	if c.record == nil {
+		c.record = make([]string, len(values))
+	}
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+			c.record[i] = csvNull
+		case []byte:
+			c.record[i] = csvBinary + hex.EncodeToString(v)
+		case string:
+			if strings.HasPrefix(v, `\`) {
+				v = `\` + v
+			}
+			c.record[i] = v
+		case bool:
+			// 1 and 0 are valid booleans in Postgres and SQLite
+			c.record[i] = "0"
+			if v {
+				c.record[i] = "1"
+			}
+		case int64:
+			c.record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			c.record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		case time.Time:
+			c.record[i] = v.Format(time.RFC3339Nano)
+		default:
+			c.record[i] = fmt.Sprint(v)
+		}
+	}
+	return errtrace.Wrap(c.w.Write(c.record))
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return errtrace.Wrap(c.w.Error())
+}
+
+// csvReader reads a CSV line per row.
+type csvReader struct {
+	r *csv.Reader
+}
+
+func (c *csvReader) Read() ([]any, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, errtrace.Wrap(errors.Join(ErrInvalidExport, err))
+	}
+
+	values := make([]any, len(record))
+	for i, s := range record {
+		switch {
+		case s == csvNull:
+			values[i] = nil
+		case strings.HasPrefix(s, csvBinary):
+			b, err := hex.DecodeString(s[len(csvBinary):])
+			if err != nil {
+				return nil, errtrace.Wrap(errors.Join(ErrInvalidExport, err))
+			}
+			values[i] = b
+		case strings.HasPrefix(s, `\\`):
+			values[i] = s[1:]
+		default:
+			values[i] = s
+		}
+	}
+	return values, nil
+}
+
+// isBinary reports whether the scanned bytes are a binary value rather than text,
+// as drivers return some text types, e.g. Postgres numeric and uuid, as bytes.
+func isBinary(dbType string, dialect string) bool {
+	if dialect == "postgres" {
+		return strings.EqualFold(dbType, "BYTEA")
+	}
+	return true
+}
+
+// normalize converts the scanned value to one of the types supported by the formats.
+func normalize(v any, binary bool) any {
+	switch v := v.(type) {
+	case []byte:
+		if !binary {
+			return string(v)
+		}
+		return bytes.Clone(v)
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	}
+	return v
+}
@@ -0,0 +1,18 @@
+package backup
+
+import "errors"
+
+// Predefined errors.
+var (
+	ErrMissedDBConnection     = errors.New("missed db connection")
+	ErrSnapshotNotSupported   = errors.New("snapshot is supported only for local SQLite databases")
+	ErrFailedToSnapshot       = errors.New("failed to snapshot database")
+	ErrInvalidFormat          = errors.New("invalid export format")
+	ErrExportDirNotEmpty      = errors.New("export directory is not empty")
+	ErrFailedToExport         = errors.New("failed to export database")
+	ErrInvalidExport          = errors.New("invalid export")
+	ErrSchemaMismatch         = errors.New("database schema doesn't match the export")
+	ErrDatabaseNotEmpty       = errors.New("database is not empty")
+	ErrFailedToImport         = errors.New("failed to import database")
+	ErrFailedToResetSequences = errors.New("failed to reset sequences")
+)
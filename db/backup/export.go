@@ -0,0 +1,317 @@
+// Package backup snapshots, exports and imports the database.
+//
+// Snapshot copies a local SQLite database with VACUUM INTO.
+// Export writes a dialect-neutral logical export of any supported database into a directory:
+//
+//	manifest.json   the format, the source dialect, the applied migrations and the tables
+//	schema.json     the source schema, see migration.Schema
+//	<table>.jsonl   the rows of each table, or <table>.csv
+//
+// Import restores the export into an empty database of any dialect
+// migrated to the same version, in a single transaction.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/migration"
+)
+
+// File names of the export.
+const (
+	manifestFile = "manifest.json"
+	schemaFile   = "schema.json"
+)
+
+// manifestVersion is the version of the export format.
+const manifestVersion = 1
+
+// progressEvery is how often the progress is reported, in rows.
+const progressEvery = 1000
+
+// Manifest describes the export.
+type Manifest struct {
+	Version   int       `json:"version"`
+	Dialect   string    `json:"dialect"`
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	// Migrations are the IDs of the migrations applied to the source database.
+	Migrations []string `json:"migrations,omitempty"`
+	// Tables are listed in the import order.
+	Tables []TableInfo `json:"tables"`
+}
+
+// TableInfo describes the exported table.
+type TableInfo struct {
+	Name    string   `json:"name"`
+	File    string   `json:"file"`
+	Columns []string `json:"columns"`
+	Rows    int64    `json:"rows"`
+}
+
+// Progress is the state of the export or import of a table.
+type Progress struct {
+	Table string
+	Rows  int64 // rows processed so far
+	Total int64 // rows of the table
+	Done  bool  // the table is processed
+}
+
+// Options defines the export and import settings.
+type Options struct {
+	// Format of the exported rows, FormatJSONL or FormatCSV. Default: FormatJSONL. Ignored by Import.
+	Format string
+	// MigrationsTable is the table of the applied migrations. It isn't exported as data,
+	// instead the applied IDs are recorded in the manifest and checked by Import. Default: migrations.
+	MigrationsTable string
+	// OnProgress is called every 1000 rows and after each table, e.g. to log the progress.
+	OnProgress func(Progress)
+}
+
+// Export writes the logical export of the database into the directory, which must be empty or missing.
+// The rows are read in a single transaction, so the export is consistent.
+func Export(ctx context.Context, conn *sql.DB, dialect db.Dialect, dir string, opts Options) (Manifest, error) {
+	if conn == nil {
+		return Manifest{}, errtrace.Wrap(ErrMissedDBConnection)
+	}
+	opts = opts.withDefaults()
+	if opts.Format != FormatJSONL && opts.Format != FormatCSV {
+		return Manifest{}, errtrace.Wrap(fmt.Errorf("%w: %q", ErrInvalidFormat, opts.Format))
+	}
+
+	if err := prepareDir(dir); err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrFailedToExport, err))
+	}
+
+	schema, migrations, err := readSchema(ctx, conn, dialect, opts.MigrationsTable)
+	if err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrFailedToExport, err))
+	}
+	order, err := importOrder(ctx, conn, dialect, schema)
+	if err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrFailedToExport, err))
+	}
+
+	manifest := Manifest{
+		Version:    manifestVersion,
+		Dialect:    string(dialect),
+		Format:     opts.Format,
+		CreatedAt:  time.Now().UTC(),
+		Migrations: migrations,
+	}
+
+	// Postgres needs a repeatable read transaction to see the same snapshot in all queries,
+	// SQLite transactions are serializable
+	txOpts := &sql.TxOptions{ReadOnly: dialect == db.DialectPostgres}
+	if dialect == db.DialectPostgres {
+		txOpts.Isolation = sql.LevelRepeatableRead
+	}
+	tx, err := conn.BeginTx(ctx, txOpts)
+	if err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrFailedToExport, err))
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, t := range order {
+		info, err := exportTable(ctx, tx, string(dialect), t, dir, opts)
+		if err != nil {
+			return Manifest{}, errtrace.Wrap(errors.Join(ErrFailedToExport, fmt.Errorf("table %s: %w", t.Name, err)))
+		}
+		manifest.Tables = append(manifest.Tables, info)
+	}
+
+	if err := writeJSON(filepath.Join(dir, schemaFile), schema); err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrFailedToExport, err))
+	}
+	// The manifest is written last, so an interrupted export can't be imported
+	if err := writeJSON(filepath.Join(dir, manifestFile), manifest); err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrFailedToExport, err))
+	}
+
+	return manifest, nil
+}
+
+// exportTable writes the rows of the table into its file, ordered by the primary key.
+func exportTable(ctx context.Context, tx *sql.Tx, dialect string, t migration.Table, dir string, opts Options) (TableInfo, error) {
+	info := TableInfo{Name: t.Name, File: t.Name + "." + opts.Format}
+	if info.File != filepath.Base(info.File) {
+		return info, errtrace.Wrap(fmt.Errorf("table name %q can't be used as a file name", t.Name))
+	}
+	var pk []string
+	for _, c := range t.Columns {
+		info.Columns = append(info.Columns, c.Name)
+		if c.PrimaryKey {
+			pk = append(pk, quote(c.Name))
+		}
+	}
+
+	var total int64
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quote(t.Name)).Scan(&total); err != nil {
+		return info, errtrace.Wrap(err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", quoteList(info.Columns), quote(t.Name))
+	if len(pk) > 0 {
+		query += " ORDER BY " + strings.Join(pk, ", ")
+	}
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return info, errtrace.Wrap(err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return info, errtrace.Wrap(err)
+	}
+	binary := make([]bool, len(types))
+	for i, ct := range types {
+		binary[i] = isBinary(ct.DatabaseTypeName(), dialect)
+	}
+
+	f, err := os.Create(filepath.Join(dir, info.File))
+	if err != nil {
+		return info, errtrace.Wrap(err)
+	}
+	defer f.Close()
+
+	w, err := newRowWriter(opts.Format, f, info.Columns)
+	if err != nil {
+		return info, errtrace.Wrap(err)
+	}
+
+	values := make([]any, len(info.Columns))
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return info, errtrace.Wrap(err)
+		}
+		for i := range values {
+			values[i] = normalize(values[i], binary[i])
+		}
+		if err := w.Write(values); err != nil {
+			return info, errtrace.Wrap(err)
+		}
+		if info.Rows++; info.Rows%progressEvery == 0 {
+			opts.progress(Progress{Table: t.Name, Rows: info.Rows, Total: total})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return info, errtrace.Wrap(err)
+	}
+
+	if err := w.Flush(); err != nil {
+		return info, errtrace.Wrap(err)
+	}
+	if err := f.Close(); err != nil {
+		return info, errtrace.Wrap(err)
+	}
+
+	opts.progress(Progress{Table: t.Name, Rows: info.Rows, Total: info.Rows, Done: true})
+	return info, nil
+}
+
+// readSchema returns the schema of the data tables and the applied migrations.
+func readSchema(ctx context.Context, conn *sql.DB, dialect db.Dialect, migrationsTable string) (migration.Schema, []string, error) {
+	schema, err := migration.ReadSchema(ctx, conn, string(dialect), migrationsTable+"_lock")
+	if err != nil {
+		return migration.Schema{}, nil, errtrace.Wrap(err)
+	}
+
+	var migrations []string
+	tables := schema.Tables[:0]
+	for _, t := range schema.Tables {
+		if t.Name != migrationsTable {
+			tables = append(tables, t)
+			continue
+		}
+		if migrations, err = appliedMigrations(ctx, conn, migrationsTable); err != nil {
+			return migration.Schema{}, nil, errtrace.Wrap(err)
+		}
+	}
+	schema.Tables = tables
+
+	return schema, migrations, nil
+}
+
+// appliedMigrations returns the IDs of the applied migrations ordered by ID.
+func appliedMigrations(ctx context.Context, conn *sql.DB, table string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT id FROM %s ORDER BY id", quote(table)))
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		result = append(result, id)
+	}
+	return result, errtrace.Wrap(rows.Err())
+}
+
+// withDefaults returns the options with the defaults applied.
+func (o Options) withDefaults() Options {
+	if o.Format == "" {
+		o.Format = FormatJSONL
+	}
+	if o.MigrationsTable == "" {
+		o.MigrationsTable = "migrations"
+	}
+	return o
+}
+
+// progress reports the progress if the callback is set.
+func (o Options) progress(p Progress) {
+	if o.OnProgress != nil {
+		o.OnProgress(p)
+	}
+}
+
+// prepareDir creates the export directory, it must be empty if it exists.
+func prepareDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err == nil && len(entries) > 0 {
+		return errtrace.Wrap(errors.Join(ErrExportDirNotEmpty, errors.New(dir)))
+	}
+	return errtrace.Wrap(os.MkdirAll(dir, 0o755))
+}
+
+// writeJSON writes the value into the file as indented JSON.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	return errtrace.Wrap(os.WriteFile(path, append(data, '\n'), 0o644))
+}
+
+// quote quotes the identifier, the quoting is the same in SQLite and Postgres.
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteList returns the comma-separated quoted identifiers.
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
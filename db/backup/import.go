@@ -0,0 +1,282 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/migration"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+)
+
+// ReadManifest reads the manifest of the export in the directory.
+func ReadManifest(dir string) (Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrInvalidExport, err))
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrInvalidExport, err))
+	}
+	if m.Version != manifestVersion {
+		return Manifest{}, errtrace.Wrap(fmt.Errorf("%w: unsupported version %d", ErrInvalidExport, m.Version))
+	}
+	if m.Format != FormatJSONL && m.Format != FormatCSV {
+		return Manifest{}, errtrace.Wrap(fmt.Errorf("%w: %q", ErrInvalidFormat, m.Format))
+	}
+
+	return m, nil
+}
+
+// Import restores the export from the directory into the database in a single transaction,
+// so the database is left unchanged if any row fails.
+//
+// The database may be of another dialect than the source, but its schema must be created
+// by the migrations of its dialect: the applied migrations must match the ones of the source,
+// the exported tables and columns must exist and the tables must be empty.
+// Postgres sequences are advanced past the imported IDs.
+func Import(ctx context.Context, conn *sql.DB, dialect db.Dialect, dir string, opts Options) (Manifest, error) {
+	if conn == nil {
+		return Manifest{}, errtrace.Wrap(ErrMissedDBConnection)
+	}
+	opts = opts.withDefaults()
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return Manifest{}, errtrace.Wrap(err)
+	}
+
+	schema, migrations, err := readSchema(ctx, conn, dialect, opts.MigrationsTable)
+	if err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrFailedToImport, err))
+	}
+	if err := checkSchema(manifest, schema, migrations); err != nil {
+		return Manifest{}, errtrace.Wrap(err)
+	}
+
+	err = db.RunInTx(ctx, conn, db.TxOptions{Dialect: dialect, MaxAttempts: 1}, func(ctx context.Context, _ repository.Querier) error {
+		tx, _ := db.TxFromContext(ctx)
+
+		for _, t := range manifest.Tables {
+			var n int64
+			if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quote(t.Name)).Scan(&n); err != nil {
+				return errtrace.Wrap(err)
+			}
+			if n > 0 {
+				return errtrace.Wrap(fmt.Errorf("%w: table %s has %d rows", ErrDatabaseNotEmpty, t.Name, n))
+			}
+		}
+
+		for _, t := range manifest.Tables {
+			if err := importTable(ctx, tx, dialect, manifest.Format, t, dir, opts); err != nil {
+				return errtrace.Wrap(fmt.Errorf("table %s: %w", t.Name, err))
+			}
+		}
+
+		if dialect == db.DialectPostgres {
+			return errtrace.Wrap(resetSequences(ctx, tx, schema, manifest))
+		}
+		return nil
+	})
+	if err != nil {
+		return Manifest{}, errtrace.Wrap(errors.Join(ErrFailedToImport, err))
+	}
+
+	return manifest, nil
+}
+
+// importTable inserts the rows of the table from its file.
+func importTable(ctx context.Context, tx *sql.Tx, dialect db.Dialect, format string, t TableInfo, dir string, opts Options) error {
+	if t.File != filepath.Base(t.File) {
+		return errtrace.Wrap(fmt.Errorf("%w: invalid file name %q", ErrInvalidExport, t.File))
+	}
+	f, err := os.Open(filepath.Join(dir, t.File))
+	if err != nil {
+		return errtrace.Wrap(errors.Join(ErrInvalidExport, err))
+	}
+	defer f.Close()
+
+	r, err := newRowReader(format, f, t.Columns)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	binds := make([]string, len(t.Columns))
+	for i := range binds {
+		binds[i] = "?"
+		if dialect == db.DialectPostgres {
+			binds[i] = fmt.Sprintf("$%d", i+1)
+		}
+	}
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quote(t.Name), quoteList(t.Columns), strings.Join(binds, ", ")))
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer stmt.Close()
+
+	var n int64
+	for {
+		values, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("row %d: %w", n+1, err))
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return errtrace.Wrap(fmt.Errorf("row %d: %w", n+1, err))
+		}
+		if n++; n%progressEvery == 0 {
+			opts.progress(Progress{Table: t.Name, Rows: n, Total: t.Rows})
+		}
+	}
+	if n != t.Rows {
+		return errtrace.Wrap(fmt.Errorf("%w: %d rows in the file, %d in the manifest", ErrInvalidExport, n, t.Rows))
+	}
+
+	opts.progress(Progress{Table: t.Name, Rows: n, Total: t.Rows, Done: true})
+	return nil
+}
+
+// checkSchema checks that the database has the migrations, the tables and the columns of the export.
+func checkSchema(m Manifest, schema migration.Schema, migrations []string) error {
+	if len(m.Migrations) > 0 && !slices.Equal(m.Migrations, migrations) {
+		return errtrace.Wrap(fmt.Errorf("%w: the export has %d applied migrations (last %s), the database has %d",
+			ErrSchemaMismatch, len(m.Migrations), m.Migrations[len(m.Migrations)-1], len(migrations)))
+	}
+
+	for _, t := range m.Tables {
+		table, ok := schema.Table(t.Name)
+		if !ok {
+			return errtrace.Wrap(fmt.Errorf("%w: missing table %s", ErrSchemaMismatch, t.Name))
+		}
+		for _, col := range t.Columns {
+			if !slices.ContainsFunc(table.Columns, func(c migration.Column) bool { return c.Name == col }) {
+				return errtrace.Wrap(fmt.Errorf("%w: missing column %s.%s", ErrSchemaMismatch, t.Name, col))
+			}
+		}
+	}
+
+	return nil
+}
+
+// resetSequences advances the sequences of the serial columns past the imported values,
+// as explicit values don't advance them.
+func resetSequences(ctx context.Context, tx *sql.Tx, schema migration.Schema, m Manifest) error {
+	for _, t := range m.Tables {
+		table, _ := schema.Table(t.Name)
+		for _, c := range table.Columns {
+			if !strings.HasPrefix(c.Default, "nextval(") {
+				continue
+			}
+			_, err := tx.ExecContext(ctx, fmt.Sprintf(
+				`SELECT setval(pg_get_serial_sequence($1, $2), COALESCE((SELECT MAX(%s) FROM %s), 0) + 1, false)`,
+				quote(c.Name), quote(t.Name)), t.Name, c.Name)
+			if err != nil {
+				return errtrace.Wrap(errors.Join(ErrFailedToResetSequences, fmt.Errorf("%s.%s: %w", t.Name, c.Name, err)))
+			}
+		}
+	}
+	return nil
+}
+
+// importOrder returns the tables ordered so the referenced tables go before the referencing ones,
+// tables without dependencies between them are ordered by name.
+// Tables in reference cycles are appended by name, their import succeeds only if the foreign keys
+// are not enforced or the references are NULL.
+func importOrder(ctx context.Context, conn *sql.DB, dialect db.Dialect, schema migration.Schema) ([]migration.Table, error) {
+	deps := make(map[string][]string, len(schema.Tables))
+	for _, t := range schema.Tables {
+		refs, err := foreignKeyTables(ctx, conn, dialect, t.Name)
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		deps[t.Name] = refs
+	}
+
+	names := make([]string, 0, len(schema.Tables))
+	for _, t := range schema.Tables {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+
+	var (
+		order []string
+		added = make(map[string]bool, len(names))
+	)
+	for len(order) < len(names) {
+		progressed := false
+		for _, name := range names {
+			if added[name] {
+				continue
+			}
+			ready := true
+			for _, ref := range deps[name] {
+				if ref != name && !added[ref] && deps[ref] != nil {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				order, added[name], progressed = append(order, name), true, true
+			}
+		}
+		if !progressed {
+			// A reference cycle, add the rest as is
+			for _, name := range names {
+				if !added[name] {
+					order, added[name] = append(order, name), true
+				}
+			}
+		}
+	}
+
+	result := make([]migration.Table, 0, len(order))
+	for _, name := range order {
+		t, _ := schema.Table(name)
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// foreignKeyTables returns the tables referenced by the foreign keys of the table.
+// The result is not nil for existing tables, so it can tell exported tables from the others.
+func foreignKeyTables(ctx context.Context, conn *sql.DB, dialect db.Dialect, table string) ([]string, error) {
+	query := `SELECT DISTINCT "table" FROM pragma_foreign_key_list(?)`
+	if dialect == db.DialectPostgres {
+		query = `SELECT DISTINCT ccu.table_name
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.constraint_column_usage ccu
+				ON ccu.constraint_schema = tc.constraint_schema AND ccu.constraint_name = tc.constraint_name
+			WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema() AND tc.table_name = $1`
+	}
+
+	rows, err := conn.QueryContext(ctx, query, table)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer rows.Close()
+
+	result := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		result = append(result, name)
+	}
+	return result, errtrace.Wrap(rows.Err())
+}
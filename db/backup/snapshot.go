@@ -0,0 +1,39 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+)
+
+// Snapshot writes a consistent copy of the local SQLite database to the file using VACUUM INTO.
+// The copy is compacted and can be opened as a regular SQLite database, e.g. to restore it
+// by replacing the database file while the application is stopped.
+// The file must not exist. Remote libSQL and Postgres databases are not supported,
+// as the file would be written on the database server, use Export instead.
+func Snapshot(ctx context.Context, conn *sql.DB, dialect db.Dialect, path string) error {
+	if conn == nil {
+		return errtrace.Wrap(ErrMissedDBConnection)
+	}
+	if dialect != db.DialectSQLite {
+		return errtrace.Wrap(ErrSnapshotNotSupported)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return errtrace.Wrap(errors.Join(ErrFailedToSnapshot, os.ErrExist, errors.New(path)))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errtrace.Wrap(errors.Join(ErrFailedToSnapshot, err))
+	}
+
+	if _, err := conn.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return errtrace.Wrap(errors.Join(ErrFailedToSnapshot, err))
+	}
+
+	return nil
+}
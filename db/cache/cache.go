@@ -0,0 +1,393 @@
+// Package cache provides the read-through caching decorator of repository.Querier.
+//
+// Reads are served from the in-process LRU first, then from Redis, and finally from the database,
+// filling both tiers on the way back. Concurrent misses of the same key are coalesced
+// into a single database query. Write methods delete the affected keys from both tiers
+// after the write succeeds, and publish them to the other instances, see Subscribe.
+//
+// Every Redis key has a version bumped by the invalidation. A loaded value is stored
+// only if the version didn't change during the load, so a load of another instance racing
+// with the write can't put the old data back into Redis.
+//
+// Only the non-transactional repository should be wrapped,
+// the repository passed to db.RunInTx must always see the data of its transaction.
+// Wrap it with WithTx instead, so the writes of the transaction invalidate the cache after the commit.
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cached methods of repository.Querier.
+const (
+	MethodGetAuthor   = "GetAuthor"
+	MethodListAuthors = "ListAuthors"
+)
+
+// Default cache settings.
+const (
+	defaultPrefix    = "querier:"
+	defaultLocalSize = 1024
+	defaultLocalTTL  = 10 * time.Second
+	// versionTTL is the lifetime of the key versions since the last invalidation,
+	// it must be longer than any load. An expired version only makes the load in flight not stored.
+	versionTTL = 24 * time.Hour
+)
+
+// DefaultTTLs are the default per-method TTLs.
+var DefaultTTLs = map[string]time.Duration{
+	MethodGetAuthor:   5 * time.Minute,
+	MethodListAuthors: time.Minute,
+}
+
+// Options defines the cache settings.
+type Options struct {
+	// TTLs are the per-method TTLs of the Redis tier, methods without a TTL are not cached.
+	// Default: DefaultTTLs.
+	TTLs map[string]time.Duration
+	// Prefix of the Redis keys and the invalidation channel. Default: "querier:".
+	Prefix string
+	// LocalSize is the maximum number of entries of the in-process tier, negative disables it. Default: 1024.
+	LocalSize int
+	// LocalTTL caps the TTL of the in-process entries, it bounds the staleness
+	// if an invalidation message is missed. Default: 10s.
+	LocalTTL time.Duration
+	// OnError is called when Redis fails, e.g. to log it. The cache falls back to the database.
+	OnError func(err error)
+}
+
+// Querier is the caching decorator of repository.Querier.
+type Querier struct {
+	next  repository.Querier
+	redis redis.UniversalClient
+	local *lru
+	group singleflight.Group
+	opts  Options
+	// epoch is incremented by every invalidation, loads started before it don't fill the cache
+	epoch atomic.Uint64
+}
+
+var _ repository.Querier = (*Querier)(nil)
+
+// New creates a new caching decorator of the repository.
+// A nil Redis client leaves only the in-process tier.
+func New(next repository.Querier, rdb redis.UniversalClient, opts Options) *Querier {
+	if opts.TTLs == nil {
+		opts.TTLs = DefaultTTLs
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = defaultPrefix
+	}
+	if opts.LocalSize == 0 {
+		opts.LocalSize = defaultLocalSize
+	}
+	if opts.LocalTTL <= 0 {
+		opts.LocalTTL = defaultLocalTTL
+	}
+
+	q := &Querier{next: next, redis: rdb, opts: opts}
+	if opts.LocalSize > 0 {
+		q.local = newLRU(opts.LocalSize)
+	}
+	return q
+}
+
+// Cache keys without the prefix.
+func authorKey(id int64) string { return "author:" + strconv.FormatInt(id, 10) }
+
+const authorsListKey = "authors:list"
+
+// GetAuthor implements repository.Querier.
+func (q *Querier) GetAuthor(ctx context.Context, id int64) (repository.Author, error) {
+	return errtrace.Wrap2(cached(ctx, q, MethodGetAuthor, authorKey(id), func(ctx context.Context) (repository.Author, error) {
+		return errtrace.Wrap2(q.next.GetAuthor(ctx, id))
+	}, nil))
+}
+
+// ListAuthors implements repository.Querier.
+func (q *Querier) ListAuthors(ctx context.Context) ([]repository.Author, error) {
+	return errtrace.Wrap2(cached(ctx, q, MethodListAuthors, authorsListKey, func(ctx context.Context) ([]repository.Author, error) {
+		return errtrace.Wrap2(q.next.ListAuthors(ctx))
+	}, slices.Clone[[]repository.Author]))
+}
+
+// CreateAuthor implements repository.Querier.
+func (q *Querier) CreateAuthor(ctx context.Context, arg repository.CreateAuthorParams) (repository.Author, error) {
+	a, err := q.next.CreateAuthor(ctx, arg)
+	if err != nil {
+		return a, errtrace.Wrap(err)
+	}
+	q.invalidate(ctx, authorKey(a.ID), authorsListKey)
+	return a, nil
+}
+
+// UpdateAuthor implements repository.Querier.
+func (q *Querier) UpdateAuthor(ctx context.Context, arg repository.UpdateAuthorParams) error {
+	if err := q.next.UpdateAuthor(ctx, arg); err != nil {
+		return errtrace.Wrap(err)
+	}
+	q.invalidate(ctx, authorKey(arg.ID), authorsListKey)
+	return nil
+}
+
+// DeleteAuthor implements repository.Querier.
+func (q *Querier) DeleteAuthor(ctx context.Context, id int64) error {
+	if err := q.next.DeleteAuthor(ctx, id); err != nil {
+		return errtrace.Wrap(err)
+	}
+	q.invalidate(ctx, authorKey(id), authorsListKey)
+	return nil
+}
+
+// The outbox is never cached, its methods are passed through to the repository.
+
+// CreateOutboxMessage implements repository.Querier.
+func (q *Querier) CreateOutboxMessage(ctx context.Context, arg repository.CreateOutboxMessageParams) (repository.Outbox, error) {
+	return errtrace.Wrap2(q.next.CreateOutboxMessage(ctx, arg))
+}
+
+// ListPendingOutboxMessages implements repository.Querier.
+func (q *Querier) ListPendingOutboxMessages(ctx context.Context, arg repository.ListPendingOutboxMessagesParams) ([]repository.Outbox, error) {
+	return errtrace.Wrap2(q.next.ListPendingOutboxMessages(ctx, arg))
+}
+
+// MarkOutboxMessagePublished implements repository.Querier.
+func (q *Querier) MarkOutboxMessagePublished(ctx context.Context, arg repository.MarkOutboxMessagePublishedParams) error {
+	return errtrace.Wrap(q.next.MarkOutboxMessagePublished(ctx, arg))
+}
+
+// MarkOutboxMessageFailed implements repository.Querier.
+func (q *Querier) MarkOutboxMessageFailed(ctx context.Context, arg repository.MarkOutboxMessageFailedParams) error {
+	return errtrace.Wrap(q.next.MarkOutboxMessageFailed(ctx, arg))
+}
+
+// DeletePublishedOutboxMessages implements repository.Querier.
+func (q *Querier) DeletePublishedOutboxMessages(ctx context.Context, before sql.NullTime) (int64, error) {
+	return errtrace.Wrap2(q.next.DeletePublishedOutboxMessages(ctx, before))
+}
+
+// cached returns the value of the key from the cache tiers or loads it with load.
+// Concurrent misses of the key share a single load, clone copies the shared value
+// for each caller if it's mutable, e.g. a slice. Errors are not cached.
+func cached[T any](ctx context.Context, q *Querier, method, key string, load func(context.Context) (T, error), clone func(T) T) (T, error) {
+	ttl, ok := q.opts.TTLs[method]
+	if !ok || ttl <= 0 {
+		return errtrace.Wrap2(load(ctx))
+	}
+	if clone == nil {
+		clone = func(v T) T { return v }
+	}
+
+	if q.local != nil {
+		if v, ok := q.local.Get(key); ok {
+			return clone(v.(T)), nil
+		}
+	}
+
+	// The shared load must not be canceled by the caller which started it,
+	// each caller stops waiting on its own context instead.
+	// Misses are loaded from the primary database: a lagging read replica would
+	// put the data replaced by a recent write into the cache for the whole TTL.
+	ch := q.group.DoChan(key, func() (any, error) {
+		loadCtx := db.WithPrimary(context.WithoutCancel(ctx))
+		epoch := q.epoch.Load()
+		v, version, ok := getRedis[T](loadCtx, q, key)
+		if ok {
+			if q.epoch.Load() == epoch {
+				q.setLocal(key, v, ttl)
+			}
+			return v, nil
+		}
+
+		v, err := load(loadCtx)
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		// A write during the load may have made the value stale
+		if q.epoch.Load() == epoch {
+			q.setRedis(loadCtx, key, version, v, ttl)
+			q.setLocal(key, v, ttl)
+		}
+		return v, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, errtrace.Wrap(ctx.Err())
+	case res := <-ch:
+		if res.Err != nil {
+			var zero T
+			return zero, errtrace.Wrap(res.Err)
+		}
+		return clone(res.Val.(T)), nil
+	}
+}
+
+// getRedis returns the value of the key from Redis and the version of the key
+// to pass to setRedis on a miss. The version is nil if it can't be read.
+func getRedis[T any](ctx context.Context, q *Querier, key string) (T, *string, bool) {
+	var v T
+	if q.redis == nil {
+		return v, nil, false
+	}
+
+	var data, version *redis.StringCmd
+	_, err := q.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		data = pipe.Get(ctx, q.dataKey(key))
+		version = pipe.Get(ctx, q.versionKey(key))
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		q.onError(err)
+		return v, nil, false
+	}
+
+	ver, err := version.Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return v, nil, false
+	}
+
+	b, err := data.Bytes()
+	if err != nil {
+		return v, &ver, false
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		q.onError(err)
+		return v, &ver, false
+	}
+	return v, &ver, true
+}
+
+// setIfVersionScript stores the value only if the version key still has the given value,
+// a missing version is an empty string.
+var setIfVersionScript = redis.NewScript(`
+local version = redis.call("GET", KEYS[2]) or ""
+if version ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
+// setRedis stores the value of the key in Redis if the key wasn't invalidated
+// since the version was read. Without the version the value is not stored.
+func (q *Querier) setRedis(ctx context.Context, key string, version *string, v any, ttl time.Duration) {
+	if q.redis == nil || version == nil {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		q.onError(err)
+		return
+	}
+	keys := []string{q.dataKey(key), q.versionKey(key)}
+	if err := setIfVersionScript.Run(ctx, q.redis, keys, *version, data, ttl.Milliseconds()).Err(); err != nil {
+		q.onError(err)
+	}
+}
+
+// setLocal stores the value of the key in the in-process tier.
+func (q *Querier) setLocal(key string, v any, ttl time.Duration) {
+	if q.local != nil {
+		q.local.Set(key, v, min(ttl, q.opts.LocalTTL))
+	}
+}
+
+// invalidate deletes the keys from both tiers and notifies the other instances.
+// It's called after the write, so the next read loads the new data.
+func (q *Querier) invalidate(ctx context.Context, keys ...string) {
+	q.epoch.Add(1)
+	for _, key := range keys {
+		q.group.Forget(key)
+	}
+	if q.local != nil {
+		q.local.Delete(keys...)
+	}
+	if q.redis == nil {
+		return
+	}
+
+	// The keys are deleted even if the request context is canceled right after the write.
+	// The version is bumped first, so the loads in flight on any instance don't store the old value.
+	ctx = context.WithoutCancel(ctx)
+	if _, err := q.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Incr(ctx, q.versionKey(key))
+			pipe.PExpire(ctx, q.versionKey(key), versionTTL)
+			pipe.Del(ctx, q.dataKey(key))
+		}
+		return nil
+	}); err != nil {
+		q.onError(err)
+	}
+	if q.local != nil {
+		if err := q.redis.Publish(ctx, q.channel(), strings.Join(keys, "\n")).Err(); err != nil {
+			q.onError(err)
+		}
+	}
+}
+
+// Subscribe evicts the keys invalidated by the other instances from the in-process tier
+// until the context is canceled, e.g. run it in the main errgroup.
+// Without Redis or the in-process tier it just waits for the context.
+func (q *Querier) Subscribe(ctx context.Context) error {
+	if q.redis == nil || q.local == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	sub := q.redis.Subscribe(ctx, q.channel())
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			q.local.Delete(strings.Split(msg.Payload, "\n")...)
+		}
+	}
+}
+
+// dataKey returns the Redis key of the cached value.
+// The key is in braces, so the value and its version are in the same slot of Redis Cluster.
+func (q *Querier) dataKey(key string) string {
+	return q.opts.Prefix + "{" + key + "}"
+}
+
+// versionKey returns the Redis key of the version of the cached value.
+func (q *Querier) versionKey(key string) string {
+	return q.opts.Prefix + "{" + key + "}:version"
+}
+
+// channel returns the name of the invalidation channel.
+func (q *Querier) channel() string {
+	return q.opts.Prefix + "invalidate"
+}
+
+// onError reports the Redis error if the callback is set.
+func (q *Querier) onError(err error) {
+	if q.opts.OnError != nil {
+		q.opts.OnError(err)
+	}
+}
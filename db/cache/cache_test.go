@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	"github.com/dmitrymomot/go-app-template/db/testdb"
+	"github.com/redis/go-redis/v9"
+)
+
+// countingQuerier counts the GetAuthor loads. If loaded is set, each load signals it
+// after the query and waits for release, so a write can happen while the load is in flight.
+type countingQuerier struct {
+	repository.Querier
+	loads   atomic.Int32
+	loaded  chan struct{}
+	release chan struct{}
+}
+
+func (c *countingQuerier) GetAuthor(ctx context.Context, id int64) (repository.Author, error) {
+	c.loads.Add(1)
+	a, err := c.Querier.GetAuthor(ctx, id)
+	if c.loaded != nil {
+		c.loaded <- struct{}{}
+		<-c.release
+	}
+	return a, err
+}
+
+// newTestRepo returns the repository of a new database with an author.
+func newTestRepo(t *testing.T) (*sql.DB, repository.Author) {
+	t.Helper()
+
+	conn := testdb.Open(t, testdb.Options{})
+	a, err := repository.New(conn).CreateAuthor(context.Background(), repository.CreateAuthorParams{Name: "Rob Pike"})
+	if err != nil {
+		t.Fatalf("CreateAuthor() error = %v", err)
+	}
+	return conn, a
+}
+
+// mustGetAuthor returns the author name read through the cache.
+func mustGetAuthor(t *testing.T, q repository.Querier, id int64) string {
+	t.Helper()
+
+	a, err := q.GetAuthor(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetAuthor() error = %v", err)
+	}
+	return a.Name
+}
+
+func TestQuerierReadThrough(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	conn, a := newTestRepo(t)
+	repo := &countingQuerier{Querier: repository.New(conn)}
+	q := New(repo, nil, Options{})
+
+	for i := 0; i < 3; i++ {
+		if name := mustGetAuthor(t, q, a.ID); name != "Rob Pike" {
+			t.Fatalf("GetAuthor() = %q, want %q", name, "Rob Pike")
+		}
+	}
+	if n := repo.loads.Load(); n != 1 {
+		t.Errorf("the author is loaded %d times, want 1", n)
+	}
+
+	if err := q.UpdateAuthor(ctx, repository.UpdateAuthorParams{ID: a.ID, Name: "Ken Thompson"}); err != nil {
+		t.Fatalf("UpdateAuthor() error = %v", err)
+	}
+	if name := mustGetAuthor(t, q, a.ID); name != "Ken Thompson" {
+		t.Errorf("GetAuthor() after the update = %q, want %q", name, "Ken Thompson")
+	}
+	if n := repo.loads.Load(); n != 2 {
+		t.Errorf("the author is loaded %d times, want 2", n)
+	}
+}
+
+func TestQuerierInvalidationDuringLoad(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	conn, a := newTestRepo(t)
+	repo := &countingQuerier{Querier: repository.New(conn), loaded: make(chan struct{}), release: make(chan struct{})}
+	q := New(repo, nil, Options{})
+
+	type result struct {
+		name string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		a, err := q.GetAuthor(ctx, a.ID)
+		done <- result{a.Name, err}
+	}()
+
+	// The load has read the old name, the update invalidates the key before the load returns
+	<-repo.loaded
+	if err := q.UpdateAuthor(ctx, repository.UpdateAuthorParams{ID: a.ID, Name: "Ken Thompson"}); err != nil {
+		t.Fatalf("UpdateAuthor() error = %v", err)
+	}
+	close(repo.release)
+
+	res := <-done
+	if res.err != nil || res.name != "Rob Pike" {
+		t.Fatalf("GetAuthor() in flight = %q, %v, want the old name", res.name, res.err)
+	}
+
+	// The stale value is not stored, so the next read loads the new one
+	repo.loaded = nil
+	if name := mustGetAuthor(t, q, a.ID); name != "Ken Thompson" {
+		t.Errorf("GetAuthor() after the update = %q, want %q", name, "Ken Thompson")
+	}
+}
+
+func TestQuerierWithTx(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	conn, a := newTestRepo(t)
+	repo := &countingQuerier{Querier: repository.New(conn)}
+	q := New(repo, nil, Options{})
+	opts := db.TxOptions{Dialect: db.DialectSQLite, WrapQuerier: q.WithTx}
+
+	mustGetAuthor(t, q, a.ID)
+
+	// The invalidation of a rolled back transaction is discarded
+	errRollback := errors.New("rollback")
+	err := db.RunInTx(ctx, conn, opts, func(ctx context.Context, tx repository.Querier) error {
+		if err := tx.UpdateAuthor(ctx, repository.UpdateAuthorParams{ID: a.ID, Name: "Ken Thompson"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("RunInTx() error = %v, want %v", err, errRollback)
+	}
+	if name := mustGetAuthor(t, q, a.ID); name != "Rob Pike" || repo.loads.Load() != 1 {
+		t.Errorf("GetAuthor() after the rollback = %q with %d loads, want the cached old name", name, repo.loads.Load())
+	}
+
+	err = db.RunInTx(ctx, conn, opts, func(ctx context.Context, tx repository.Querier) error {
+		if err := tx.UpdateAuthor(ctx, repository.UpdateAuthorParams{ID: a.ID, Name: "Ken Thompson"}); err != nil {
+			return err
+		}
+		// The cache is invalidated after the commit, a read before it would cache the old data again
+		if name := mustGetAuthor(t, q, a.ID); name != "Rob Pike" {
+			t.Errorf("GetAuthor() before the commit = %q, want the cached old name", name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunInTx() error = %v", err)
+	}
+	if name := mustGetAuthor(t, q, a.ID); name != "Ken Thompson" {
+		t.Errorf("GetAuthor() after the commit = %q, want %q", name, "Ken Thompson")
+	}
+}
+
+// TestRedisVersion checks that a load racing with the invalidation made by another instance
+// doesn't store the old data in Redis. It runs against the Redis server from TEST_REDIS_URL,
+// e.g. redis://localhost:6379/0, and is skipped if the variable is not set.
+func TestRedisVersion(t *testing.T) {
+	redisURL := os.Getenv("TEST_REDIS_URL")
+	if redisURL == "" {
+		t.Skip("TEST_REDIS_URL is not set")
+	}
+	ctx := context.Background()
+
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		t.Fatalf("failed to parse redis url: %v", err)
+	}
+	rdb := redis.NewClient(opt)
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	conn, a := newTestRepo(t)
+	// The instances don't have the in-process tier, so only Redis is checked
+	opts := Options{Prefix: fmt.Sprintf("test:%d:", time.Now().UnixNano()), LocalSize: -1}
+	repo := &countingQuerier{Querier: repository.New(conn), loaded: make(chan struct{}), release: make(chan struct{})}
+	loader, writer := New(repo, rdb, opts), New(repository.New(conn), rdb, opts)
+	key := loader.dataKey(authorKey(a.ID))
+	t.Cleanup(func() { rdb.Del(ctx, key, loader.versionKey(authorKey(a.ID))) })
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := loader.GetAuthor(ctx, a.ID)
+		done <- err
+	}()
+
+	<-repo.loaded
+	if err := writer.UpdateAuthor(ctx, repository.UpdateAuthorParams{ID: a.ID, Name: "Ken Thompson"}); err != nil {
+		t.Fatalf("UpdateAuthor() error = %v", err)
+	}
+	close(repo.release)
+	if err := <-done; err != nil {
+		t.Fatalf("GetAuthor() error = %v", err)
+	}
+
+	if n, err := rdb.Exists(ctx, key).Result(); err != nil || n != 0 {
+		t.Fatalf("Exists() = %d, %v after the racing load, want the old data not stored", n, err)
+	}
+
+	// A load after the invalidation is stored
+	repo.loaded = nil
+	if name := mustGetAuthor(t, loader, a.ID); name != "Ken Thompson" {
+		t.Errorf("GetAuthor() after the update = %q, want %q", name, "Ken Thompson")
+	}
+	if n, err := rdb.Exists(ctx, key).Result(); err != nil || n != 1 {
+		t.Errorf("Exists() = %d, %v after the load, want the new data stored", n, err)
+	}
+	if name := mustGetAuthor(t, writer, a.ID); name != "Ken Thompson" {
+		t.Errorf("GetAuthor() of the other instance = %q, want %q", name, "Ken Thompson")
+	}
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEviction(t *testing.T) {
+	t.Parallel()
+
+	c := newLRU(2)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+	// a is used, so b becomes the least recently used entry
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v, want 1, true", v, ok)
+	}
+	c.Set("c", 3, time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Get(b) found the least recently used entry, want it evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := c.Get(key); !ok || v != want {
+			t.Errorf("Get(%s) = %v, %v, want %d, true", key, v, ok, want)
+		}
+	}
+
+	// Updating an entry doesn't evict anything
+	c.Set("a", 10, time.Minute)
+	if v, ok := c.Get("a"); !ok || v != 10 {
+		t.Errorf("Get(a) after update = %v, %v, want 10, true", v, ok)
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c) after update of a = %v, %v, want 3, true", v, ok)
+	}
+	if c.order.Len() != len(c.items) || len(c.items) != 2 {
+		t.Errorf("cache has %d list elements and %d items, want 2", c.order.Len(), len(c.items))
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	t.Parallel()
+
+	c := newLRU(10)
+	c.Set("short", 1, 10*time.Millisecond)
+	c.Set("long", 2, time.Minute)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := c.Get("short"); ok {
+		t.Errorf("Get(short) found the expired entry")
+	}
+	if _, ok := c.items["short"]; ok {
+		t.Errorf("the expired entry is not removed by Get")
+	}
+	if v, ok := c.Get("long"); !ok || v != 2 {
+		t.Errorf("Get(long) = %v, %v, want 2, true", v, ok)
+	}
+
+	// Set renews the expiration of an existing entry
+	c.Set("long", 3, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("long"); ok {
+		t.Errorf("Get(long) found the entry after its renewed TTL")
+	}
+}
+
+func TestLRUDelete(t *testing.T) {
+	t.Parallel()
+
+	c := newLRU(10)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+	c.Delete("a", "missing")
+
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get(a) found the deleted entry")
+	}
+	if v, ok := c.Get("b"); !ok || v != 2 {
+		t.Errorf("Get(b) = %v, %v, want 2, true", v, ok)
+	}
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+)
+
+// txQuerier is the repository of a transaction which invalidates the cache after the commit.
+// Reads are not cached, they must see the uncommitted data of the transaction.
+type txQuerier struct {
+	repository.Querier
+	cache *Querier
+}
+
+var _ repository.Querier = (*txQuerier)(nil)
+
+// WithTx wraps the repository of a transaction, so its writes invalidate the cached data
+// once the transaction is committed, and are discarded with it if it's rolled back:
+//
+//	err := db.RunInTx(ctx, conn, db.TxOptions{Dialect: dialect, WrapQuerier: queryCache.WithTx}, fn)
+//
+// The write methods must be called with the context passed to the db.TxFunc, see db.AfterCommit.
+func (q *Querier) WithTx(tx repository.Querier) repository.Querier {
+	return &txQuerier{Querier: tx, cache: q}
+}
+
+// CreateAuthor implements repository.Querier.
+func (t *txQuerier) CreateAuthor(ctx context.Context, arg repository.CreateAuthorParams) (repository.Author, error) {
+	a, err := t.Querier.CreateAuthor(ctx, arg)
+	if err != nil {
+		return a, errtrace.Wrap(err)
+	}
+	t.invalidateAfterCommit(ctx, authorKey(a.ID), authorsListKey)
+	return a, nil
+}
+
+// UpdateAuthor implements repository.Querier.
+func (t *txQuerier) UpdateAuthor(ctx context.Context, arg repository.UpdateAuthorParams) error {
+	if err := t.Querier.UpdateAuthor(ctx, arg); err != nil {
+		return errtrace.Wrap(err)
+	}
+	t.invalidateAfterCommit(ctx, authorKey(arg.ID), authorsListKey)
+	return nil
+}
+
+// DeleteAuthor implements repository.Querier.
+func (t *txQuerier) DeleteAuthor(ctx context.Context, id int64) error {
+	if err := t.Querier.DeleteAuthor(ctx, id); err != nil {
+		return errtrace.Wrap(err)
+	}
+	t.invalidateAfterCommit(ctx, authorKey(id), authorsListKey)
+	return nil
+}
+
+// invalidateAfterCommit invalidates the keys after the transaction is committed.
+// Invalidating them before the commit would let a concurrent read put the old data back.
+func (t *txQuerier) invalidateAfterCommit(ctx context.Context, keys ...string) {
+	db.AfterCommit(ctx, func(ctx context.Context) {
+		t.cache.invalidate(ctx, keys...)
+	})
+}
@@ -1,16 +1,104 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"braces.dev/errtrace"
 )
 
+// Connection retry settings.
+const (
+	connectBackoff     = 100 * time.Millisecond
+	connectMaxBackoff  = 5 * time.Second
+	pingAttemptTimeout = 5 * time.Second
+)
+
 // InitDB initializes a database connection using the specified driver, connection string,
 // maximum open connections, and maximum idle connections.
 // It returns a pointer to the sql.DB object and an error if any occurred during the initialization process.
 func InitDB(driver, dbConnString string, dbMaxOpenConns, dbMaxIdleConns int) (*sql.DB, error) {
+	return errtrace.Wrap2(InitDBContext(context.Background(), driver, dbConnString, Options{
+		MaxOpenConns: dbMaxOpenConns,
+		MaxIdleConns: dbMaxIdleConns,
+	}))
+}
+
+// InitDBContext initializes a database connection using the specified driver and connection string,
+// applies the connection pool settings and checks the connection.
+// If opts.ConnectTimeout is set, the connection check is retried with exponential backoff
+// until the database is reachable or the timeout is reached, e.g. while the database container is starting.
+func InitDBContext(ctx context.Context, driver, dbConnString string, opts Options) (*sql.DB, error) {
+	db, err := open(driver, dbConnString, opts)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	if err := ping(ctx, db, opts); err != nil {
+		_ = db.Close()
+		return nil, errtrace.Wrap(err)
+	}
+
+	return db, nil
+}
+
+// HealthProbe returns the function which checks that the database is reachable,
+// e.g. for the readiness endpoint. Each check is limited by the timeout, zero means no limit.
+func HealthProbe(db *sql.DB, timeout time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if err := db.PingContext(ctx); err != nil {
+			return errtrace.Wrap(errors.Join(ErrFailedToPingDB, err))
+		}
+		return nil
+	}
+}
+
+// ping checks the database connection, retrying until opts.ConnectTimeout is reached.
+func ping(ctx context.Context, db *sql.DB, opts Options) error {
+	if opts.ConnectTimeout <= 0 {
+		if err := db.PingContext(ctx); err != nil {
+			return errtrace.Wrap(errors.Join(ErrFailedToPingDB, err))
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.ConnectTimeout)
+	defer cancel()
+
+	backoff := connectBackoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancelAttempt := context.WithTimeout(ctx, pingAttemptTimeout)
+		err := db.PingContext(attemptCtx)
+		cancelAttempt()
+		if err == nil {
+			return nil
+		}
+
+		if opts.OnConnectRetry != nil {
+			opts.OnConnectRetry(attempt, err, backoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errtrace.Wrap(errors.Join(ErrFailedToPingDB, err, ctx.Err()))
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > connectMaxBackoff {
+			backoff = connectMaxBackoff
+		}
+	}
+}
+
+// open validates the input parameters, opens a database connection
+// and sets the connection pool settings. It does not check the connection.
+func open(driver, dbConnString string, opts Options) (*sql.DB, error) {
 	// Validate input parameters
 	if dbConnString == "" {
 		return nil, errtrace.Wrap(ErrEmptyDBConnString)
@@ -18,11 +106,11 @@ func InitDB(driver, dbConnString string, dbMaxOpenConns, dbMaxIdleConns int) (*s
 	if driver == "" {
 		return nil, errtrace.Wrap(ErrUndefinedDBDriver)
 	}
-	if dbMaxOpenConns <= 0 {
-		dbMaxOpenConns = 1
+	if opts.MaxOpenConns <= 0 {
+		opts.MaxOpenConns = 1
 	}
-	if dbMaxIdleConns <= 0 {
-		dbMaxIdleConns = 1
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = 1
 	}
 
 	// Init db connection
@@ -32,19 +120,16 @@ func InitDB(driver, dbConnString string, dbMaxOpenConns, dbMaxIdleConns int) (*s
 	}
 
 	// Set db connection pool settings
-	if dbMaxOpenConns < dbMaxIdleConns {
-		dbMaxOpenConns = dbMaxIdleConns
-	}
-	if dbMaxIdleConns > 0 {
-		db.SetMaxOpenConns(dbMaxOpenConns)
+	if opts.MaxOpenConns < opts.MaxIdleConns {
+		opts.MaxOpenConns = opts.MaxIdleConns
 	}
-	if dbMaxIdleConns > 0 {
-		db.SetMaxIdleConns(dbMaxIdleConns)
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
 	}
-
-	// check db connection
-	if err := db.Ping(); err != nil {
-		return nil, errtrace.Wrap(errors.Join(ErrFailedToPingDB, err))
+	if opts.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
 	}
 
 	return db, nil
@@ -0,0 +1,129 @@
+// Package dberr maps driver-specific database errors to driver-agnostic sentinel errors,
+// so callers can tell "not found" from "unique violation" without string matching.
+//
+// Supported sources are database/sql (sql.ErrNoRows), Postgres drivers exposing the SQLSTATE code
+// (lib/pq, pgx), SQLite drivers exposing the extended result code, and the libSQL drivers
+// which report SQLite errors as text only.
+package dberr
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// Error is a classified database error.
+// It matches both its kind and the original error with errors.Is and errors.As.
+type Error struct {
+	Kind error // one of ErrNotFound, ErrConflict, ErrConstraint, ErrBusy
+	Err  error // original error
+}
+
+// Error returns the message of the original error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the kind and the original error.
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Classify wraps err with the matching kind of database error.
+// It returns err as is if it's nil, already classified or unknown.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	if kind := kindOf(err); kind != nil {
+		return &Error{Kind: kind, Err: err}
+	}
+
+	return err
+}
+
+// Is reports whether err is a database error of the given kind,
+// err doesn't need to be classified in advance.
+func Is(err, kind error) bool {
+	return errors.Is(Classify(err), kind)
+}
+
+// kindOf returns the kind of the error or nil if it's unknown.
+func kindOf(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	// Postgres SQLSTATE codes: https://www.postgresql.org/docs/current/errcodes-appendix.html
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		switch code := pgErr.SQLState(); {
+		case code == "23505": // unique_violation
+			return ErrConflict
+		case strings.HasPrefix(code, "23"): // integrity_constraint_violation class
+			return ErrConstraint
+		case code == "40001", code == "40P01", code == "55P03": // serialization_failure, deadlock_detected, lock_not_available
+			return ErrBusy
+		}
+		return nil
+	}
+
+	// SQLite extended result codes: https://www.sqlite.org/rescode.html
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		if kind := kindOfSQLiteCode(sqliteErr.Code()); kind != nil {
+			return kind
+		}
+	}
+
+	return kindOfMessage(err.Error())
+}
+
+// SQLite result codes.
+const (
+	sqliteBusy                 = 5
+	sqliteLocked               = 6
+	sqliteConstraint           = 19
+	sqliteConstraintPrimaryKey = sqliteConstraint | 6<<8
+	sqliteConstraintUnique     = sqliteConstraint | 8<<8
+)
+
+// kindOfSQLiteCode returns the kind of the SQLite (extended) result code.
+func kindOfSQLiteCode(code int) error {
+	switch {
+	case code == sqliteConstraintUnique, code == sqliteConstraintPrimaryKey:
+		return ErrConflict
+	case code&0xff == sqliteConstraint:
+		return ErrConstraint
+	case code&0xff == sqliteBusy, code&0xff == sqliteLocked:
+		return ErrBusy
+	}
+	return nil
+}
+
+// kindOfMessage returns the kind of the SQLite error by its message,
+// as the libSQL drivers don't expose the result codes.
+func kindOfMessage(msg string) error {
+	msg = strings.ToLower(msg)
+	switch {
+	case strings.Contains(msg, "unique constraint failed"),
+		strings.Contains(msg, "sqlite_constraint_unique"),
+		strings.Contains(msg, "sqlite_constraint_primarykey"):
+		return ErrConflict
+	case strings.Contains(msg, "constraint failed"),
+		strings.Contains(msg, "sqlite_constraint"):
+		return ErrConstraint
+	case strings.Contains(msg, "database is locked"),
+		strings.Contains(msg, "database table is locked"),
+		strings.Contains(msg, "sqlite_busy"),
+		strings.Contains(msg, "sqlite_locked"):
+		return ErrBusy
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package dberr
+
+import "errors"
+
+// Classified database errors.
+// Use errors.Is on the result of Classify to check the error kind.
+var (
+	ErrNotFound   = errors.New("record not found")
+	ErrConflict   = errors.New("record already exists")
+	ErrConstraint = errors.New("constraint violation")
+	ErrBusy       = errors.New("database is busy")
+)
@@ -0,0 +1,35 @@
+package dberr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HTTPStatus returns the HTTP status code for the database error:
+// 404 for ErrNotFound, 409 for ErrConflict, 422 for ErrConstraint and 503 for ErrBusy.
+// It returns 0 if err is not a known database error.
+func HTTPStatus(err error) int {
+	err = Classify(err)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrConstraint):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrBusy):
+		return http.StatusServiceUnavailable
+	}
+	return 0
+}
+
+// Message returns the user-safe message for the database error,
+// driver messages may contain table and column names and must not be shown to users.
+// It returns an empty string if err is not a known database error.
+func Message(err error) string {
+	var e *Error
+	if errors.As(Classify(err), &e) {
+		return e.Kind.Error()
+	}
+	return ""
+}
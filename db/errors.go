@@ -8,4 +8,13 @@ var (
 	ErrFailedToPingDB           = errors.New("failed to ping db")
 	ErrEmptyDBConnString        = errors.New("empty db connection string")
 	ErrUndefinedDBDriver        = errors.New("undefined db driver")
+	ErrUndefinedDialect         = errors.New("undefined db dialect")
+	ErrInvalidDBURL             = errors.New("invalid db url")
+	ErrUnsupportedDBScheme      = errors.New("unsupported db url scheme")
+	ErrDriverNotRegistered      = errors.New("db driver is not registered")
+	ErrFailedToBeginTx          = errors.New("failed to begin transaction")
+	ErrFailedToCommitTx         = errors.New("failed to commit transaction")
+	ErrFailedToRollbackTx       = errors.New("failed to roll back transaction")
+	ErrFailedToCreateSavepoint  = errors.New("failed to create savepoint")
+	ErrFailedToReleaseSavepoint = errors.New("failed to release savepoint")
 )
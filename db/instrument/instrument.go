@@ -0,0 +1,121 @@
+// Package instrument provides the repository.DBTX wrapper which logs slow queries,
+// records per-query latency histograms and counts queries per HTTP request.
+//
+//	q := repository.New(instrument.Wrap(db, instrument.Options{...}))
+//
+// Queries are identified by the sqlc query name parsed from the "-- name:" comment.
+package instrument
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	"go.uber.org/zap"
+)
+
+// Options defines the instrumentation settings.
+type Options struct {
+	// SlowThreshold is the duration above which queries are logged as slow, zero disables the log.
+	SlowThreshold time.Duration
+	// Metrics receives the query latencies, nil disables the histograms.
+	Metrics *Metrics
+	// Logger is used for the slow query log.
+	Logger *zap.SugaredLogger
+}
+
+// DB is the instrumented repository.DBTX.
+// Query durations are measured until the query returns, rows iteration is not included.
+type DB struct {
+	db   repository.DBTX
+	opts Options
+}
+
+// Wrap returns the instrumented db.
+func Wrap(db repository.DBTX, opts Options) *DB {
+	if opts.Logger == nil {
+		opts.Logger = zap.NewNop().Sugar()
+	}
+	return &DB{db: db, opts: opts}
+}
+
+// ExecContext implements repository.DBTX.
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := d.db.ExecContext(ctx, query, args...)
+	d.observe(ctx, query, start, err)
+	return res, errtrace.Wrap(err)
+}
+
+// PrepareContext implements repository.DBTX.
+// Only the preparation is measured, not the statement executions.
+func (d *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	start := time.Now()
+	stmt, err := d.db.PrepareContext(ctx, query)
+	d.observe(ctx, query, start, err)
+	return stmt, errtrace.Wrap(err)
+}
+
+// QueryContext implements repository.DBTX.
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	d.observe(ctx, query, start, err)
+	return rows, errtrace.Wrap(err)
+}
+
+// QueryRowContext implements repository.DBTX.
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := d.db.QueryRowContext(ctx, query, args...)
+	d.observe(ctx, query, start, row.Err())
+	return row
+}
+
+// observe records the executed query.
+func (d *DB) observe(ctx context.Context, query string, start time.Time, err error) {
+	duration := time.Since(start)
+	name := QueryName(query)
+
+	if d.opts.Metrics != nil {
+		d.opts.Metrics.Observe(name, duration)
+	}
+	if stats := statsFromContext(ctx); stats != nil {
+		stats.add(name)
+	}
+	if d.opts.SlowThreshold > 0 && duration >= d.opts.SlowThreshold {
+		d.opts.Logger.Warnw("Slow query",
+			"query", name,
+			"duration", duration,
+			"threshold", d.opts.SlowThreshold,
+			"error", err,
+		)
+	}
+}
+
+var (
+	// sqlc query name comment, e.g. "-- name: GetAuthor :one"
+	queryNameRe = regexp.MustCompile(`--\s*name:\s*(\w+)`)
+	spacesRe    = regexp.MustCompile(`\s+`)
+)
+
+// maxQueryNameLength limits the name of queries without the sqlc comment.
+const maxQueryNameLength = 64
+
+// QueryName returns the sqlc query name, or the beginning of the normalized query
+// if it has no "-- name:" comment.
+func QueryName(query string) string {
+	if m := queryNameRe.FindStringSubmatch(query); m != nil {
+		return m[1]
+	}
+
+	query = strings.TrimSpace(spacesRe.ReplaceAllString(query, " "))
+	if len(query) > maxQueryNameLength {
+		query = query[:maxQueryNameLength] + "..."
+	}
+	return query
+}
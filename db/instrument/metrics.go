@@ -0,0 +1,110 @@
+package instrument
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds of the latency histogram buckets.
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// Metrics holds the per-query latency histograms.
+// It implements expvar.Var, so it can be published and read at /debug/vars:
+//
+//	expvar.Publish("db_queries", metrics)
+type Metrics struct {
+	buckets []time.Duration
+
+	mu      sync.Mutex
+	queries map[string]*histogram
+}
+
+// histogram is the latency histogram of a single query.
+type histogram struct {
+	count  uint64
+	sum    time.Duration
+	counts []uint64 // per bucket, the last one is for values above the highest bound
+}
+
+// Histogram is the snapshot of the latency histogram of a single query.
+type Histogram struct {
+	Count uint64 `json:"count"`
+	// SumMs is the total duration of all queries in milliseconds.
+	SumMs float64 `json:"sum_ms"`
+	// Buckets are the cumulative counts of queries by the bucket upper bound, e.g. "le_10ms".
+	Buckets map[string]uint64 `json:"buckets"`
+}
+
+// NewMetrics creates new histograms with the given bucket bounds in ascending order,
+// DefaultBuckets are used if none are given.
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Metrics{buckets: buckets, queries: make(map[string]*histogram)}
+}
+
+// Observe records the query duration.
+func (m *Metrics) Observe(query string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.queries[query]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets)+1)}
+		m.queries[query] = h
+	}
+
+	h.count++
+	h.sum += d
+	i := 0
+	for i < len(m.buckets) && d > m.buckets[i] {
+		i++
+	}
+	h.counts[i]++
+}
+
+// Snapshot returns the histograms by query name.
+func (m *Metrics) Snapshot() map[string]Histogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]Histogram, len(m.queries))
+	for name, h := range m.queries {
+		s := Histogram{
+			Count:   h.count,
+			SumMs:   float64(h.sum) / float64(time.Millisecond),
+			Buckets: make(map[string]uint64, len(h.counts)),
+		}
+		var cumulative uint64
+		for i, b := range m.buckets {
+			cumulative += h.counts[i]
+			s.Buckets["le_"+b.String()] = cumulative
+		}
+		s.Buckets["le_inf"] = h.count
+		result[name] = s
+	}
+
+	return result
+}
+
+// String implements expvar.Var.
+func (m *Metrics) String() string {
+	b, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
@@ -0,0 +1,98 @@
+package instrument
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// RequestStats counts the queries executed while handling a single request.
+type RequestStats struct {
+	mu      sync.Mutex
+	total   int
+	queries map[string]int
+}
+
+// Total returns the number of executed queries.
+func (s *RequestStats) Total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
+
+// Queries returns the number of executions by query name.
+func (s *RequestStats) Queries() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]int, len(s.queries))
+	for name, n := range s.queries {
+		result[name] = n
+	}
+	return result
+}
+
+// add records the query execution.
+func (s *RequestStats) add(query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	s.queries[query]++
+}
+
+// statsKey is the context key of the request stats.
+type statsKey struct{}
+
+// WithRequestStats returns the context which counts the queries executed with it.
+func WithRequestStats(ctx context.Context) (context.Context, *RequestStats) {
+	stats := &RequestStats{queries: make(map[string]int)}
+	return context.WithValue(ctx, statsKey{}, stats), stats
+}
+
+// statsFromContext returns the request stats or nil.
+func statsFromContext(ctx context.Context) *RequestStats {
+	stats, _ := ctx.Value(statsKey{}).(*RequestStats)
+	return stats
+}
+
+// Middleware counts the queries of each request and logs the total at debug level.
+// If nPlusOne is greater than zero, it warns when the request runs the same query
+// more than nPlusOne times, which usually means an N+1 query pattern; use it in debug mode.
+func Middleware(log *zap.SugaredLogger, nPlusOne int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, stats := WithRequestStats(r.Context())
+			next.ServeHTTP(w, r.WithContext(ctx))
+
+			total := stats.Total()
+			if total == 0 {
+				return
+			}
+			log.Debugw("Database queries", "method", r.Method, "path", r.URL.Path, "count", total)
+
+			if nPlusOne <= 0 {
+				return
+			}
+			queries := stats.Queries()
+			names := make([]string, 0, len(queries))
+			for name := range queries {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if n := queries[name]; n > nPlusOne {
+					log.Warnw("Possible N+1 query: the same query is run many times in one request",
+						"method", r.Method,
+						"path", r.URL.Path,
+						"query", name,
+						"count", n,
+						"threshold", nPlusOne,
+					)
+				}
+			}
+		})
+	}
+}
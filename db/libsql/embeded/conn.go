@@ -1,43 +1,189 @@
 package libsql_embeded
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"braces.dev/errtrace"
-	"github.com/libsql/go-libsql" // init libSQL driver
+	"github.com/tursodatabase/go-libsql" // init libSQL driver
+	"go.uber.org/zap"
 )
 
-// Connect establishes a connection to a libSQL/SQLite database.
-// If dbConnString is empty, a connection to an in-memory database is created.
-// It returns a pointer to the sql.DB object and an error if any.
-func Connect(dbName, primaryUrl, authToken, tempDir string) (*sql.DB, error) {
-	if dbName == "" {
-		return nil, errtrace.Wrap(ErrMissedDBName)
+// Config defines the embedded replica settings.
+type Config struct {
+	// Path to the local replica file. The file and its parent directories are created if missing.
+	// The file is kept between restarts, so only new frames are pulled from the primary.
+	Path string
+	// PrimaryURL is the URL of the primary database, e.g. libsql://[db]-[org].turso.io.
+	PrimaryURL string
+	// AuthToken is used to authenticate on the primary database.
+	// If empty, the authToken query parameter of PrimaryURL is used, if any.
+	AuthToken string
+	// SyncInterval defines how often the replica is synced with the primary.
+	// Zero disables periodic sync, use Replica.Sync to sync manually.
+	SyncInterval time.Duration
+	// Logger is used to report sync results and errors. Optional.
+	Logger *zap.SugaredLogger
+}
+
+// Replica is an embedded replica of a remote libSQL database.
+// Reads are served from the local file, writes are forwarded to the primary.
+// The replica owns the underlying connector, so it must be closed with Replica.Close.
+type Replica struct {
+	db        *sql.DB
+	connector *libsql.Connector
+	log       *zap.SugaredLogger
+
+	mu     sync.Mutex
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// Connect creates an embedded replica of the primary database, performs the initial sync
+// and starts periodic sync if Config.SyncInterval is set.
+func Connect(cfg Config) (*Replica, error) {
+	if cfg.Path == "" {
+		return nil, errtrace.Wrap(ErrMissedDBPath)
 	}
-	if primaryUrl == "" {
+	if cfg.PrimaryURL == "" {
 		return nil, errtrace.Wrap(ErrMissedPrimaryURL)
 	}
-	if authToken == "" {
-		return nil, errtrace.Wrap(ErrMissedAuthToken)
+	if cfg.SyncInterval < 0 {
+		return nil, errtrace.Wrap(ErrInvalidSyncInterval)
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop().Sugar()
 	}
 
-	// Create a temporary directory to store the database file
-	dir, err := os.MkdirTemp(tempDir, "libsql-*")
+	primaryURL, authToken, err := splitAuthToken(cfg.PrimaryURL, cfg.AuthToken)
 	if err != nil {
-		return nil, errtrace.Wrap(errors.Join(ErrFailedToCreateTempDir, err))
+		return nil, errtrace.Wrap(err)
 	}
-	defer os.RemoveAll(dir) //nolint:errcheck
 
-	dbPath := filepath.Join(dir, dbName)
+	// Create the directory to store the database file
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToCreateDir, err))
+	}
 
-	connector, err := libsql.NewEmbeddedReplicaConnector(dbPath, primaryUrl, authToken)
+	opts := make([]libsql.Option, 0, 1)
+	if authToken != "" {
+		opts = append(opts, libsql.WithAuthToken(authToken))
+	}
+
+	// The connector performs the initial sync before returning.
+	connector, err := libsql.NewEmbeddedReplicaConnector(cfg.Path, primaryURL, opts...)
 	if err != nil {
 		return nil, errtrace.Wrap(errors.Join(ErrFailedToCreateConnector, err))
 	}
-	defer connector.Close()
 
-	return sql.OpenDB(connector), nil
+	r := &Replica{
+		db:        sql.OpenDB(connector),
+		connector: connector,
+		log:       cfg.Logger,
+		stop:      make(chan struct{}),
+	}
+
+	if cfg.SyncInterval > 0 {
+		r.wg.Add(1)
+		go r.syncPeriodically(cfg.SyncInterval)
+	}
+
+	return r, nil
+}
+
+// DB returns the database handle backed by the replica.
+func (r *Replica) DB() *sql.DB {
+	return r.db
+}
+
+// Sync pulls new frames from the primary database.
+// It returns the number of synced frames.
+func (r *Replica) Sync(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, errtrace.Wrap(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, errtrace.Wrap(ErrReplicaClosed)
+	}
+
+	rep, err := r.connector.Sync()
+	if err != nil {
+		return 0, errtrace.Wrap(errors.Join(ErrFailedToSync, err))
+	}
+
+	return rep.FramesSynced, nil
+}
+
+// Close stops periodic sync, closes the database handle and releases the connector.
+// It is safe to call Close multiple times.
+func (r *Replica) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.stop)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+
+	return errtrace.Wrap(errors.Join(r.db.Close(), r.connector.Close()))
+}
+
+// syncPeriodically syncs the replica every interval until the replica is closed.
+// Sync errors are logged and do not stop the loop.
+func (r *Replica) syncPeriodically(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			n, err := r.Sync(context.Background())
+			if err != nil {
+				if errors.Is(err, ErrReplicaClosed) {
+					return
+				}
+				r.log.Errorw("Failed to sync embedded replica", "error", err)
+				continue
+			}
+			if n > 0 {
+				r.log.Debugw("Embedded replica synced", "frames", n)
+			}
+		}
+	}
+}
+
+// splitAuthToken removes the authToken query parameter from the URL.
+// The explicitly passed token takes precedence over the one from the URL.
+func splitAuthToken(primaryURL, authToken string) (string, string, error) {
+	u, err := url.Parse(primaryURL)
+	if err != nil {
+		return "", "", errtrace.Wrap(errors.Join(ErrInvalidPrimaryURL, err))
+	}
+
+	q := u.Query()
+	if authToken == "" {
+		authToken = q.Get("authToken")
+	}
+	q.Del("authToken")
+	u.RawQuery = q.Encode()
+
+	return u.String(), authToken, nil
 }
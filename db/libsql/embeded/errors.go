@@ -6,9 +6,12 @@ import (
 
 // Predefined errors
 var (
-	ErrMissedDBName            = errors.New("empty database name")
+	ErrMissedDBPath            = errors.New("empty database path")
 	ErrMissedPrimaryURL        = errors.New("empty primary URL")
-	ErrMissedAuthToken         = errors.New("empty auth token")
-	ErrFailedToCreateTempDir   = errors.New("failed to create a temporary directory")
+	ErrInvalidPrimaryURL       = errors.New("invalid primary URL")
+	ErrInvalidSyncInterval     = errors.New("sync interval must not be negative")
+	ErrFailedToCreateDir       = errors.New("failed to create the database directory")
 	ErrFailedToCreateConnector = errors.New("failed to create a connector")
+	ErrFailedToSync            = errors.New("failed to sync the embedded replica")
+	ErrReplicaClosed           = errors.New("embedded replica is closed")
 )
@@ -5,7 +5,7 @@ import (
 
 	"braces.dev/errtrace"
 	"github.com/dmitrymomot/go-app-template/db"
-	_ "github.com/libsql/go-libsql" // init libSQL driver (it's fully compatible with the sqlite3)
+	_ "github.com/tursodatabase/go-libsql" // init libSQL driver (it's fully compatible with the sqlite3)
 )
 
 // Connect establishes a connection to a libSQL/SQLite database.
@@ -10,6 +10,9 @@ import (
 
 // Connect opens libSQL database connection.
 // Can be used also for SQLite3 database connection (libsql driver).
+// The pure Go libsql-client-go driver registers the same "libsql" driver name
+// as github.com/tursodatabase/go-libsql, so this package cannot be linked into a binary
+// that uses db.Open, libsql_local or libsql_embeded.
 func Connect(dbConnString string, dbMaxOpenConns, dbMaxIdleConns int) (*sql.DB, error) {
 	if dbConnString == "" {
 		return nil, errtrace.Wrap(db.ErrEmptyDBConnString)
@@ -0,0 +1,86 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"braces.dev/errtrace"
+)
+
+// Drift is the result of the comparison of the database schema with the migrations.
+type Drift struct {
+	// Applied is the number of migrations replayed to build the expected schema.
+	Applied int `json:"applied"`
+	// Pending is the number of migrations not applied to the database yet,
+	// they are not taken into account.
+	Pending int      `json:"pending"`
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Schema returns the current schema of the database without the migrator's own tables.
+func (m *Migrator) Schema(ctx context.Context) (Schema, error) {
+	return errtrace.Wrap2(ReadSchema(ctx, m.db, m.dialect, m.table, m.table+"_lock"))
+}
+
+// Diff compares the schema of the database with the schema produced by replaying
+// the migrations applied to it against the empty reference database of the same dialect,
+// e.g. ScratchDB. Migrations are assumed to be applied in order.
+//
+// "missing" changes are expected by the migrations but absent in the database,
+// "extra" changes exist in the database only, e.g. hand-applied hotfixes.
+func (m *Migrator) Diff(ctx context.Context, reference *sql.DB) (Drift, error) {
+	list, err := m.Status()
+	if err != nil {
+		return Drift{}, errtrace.Wrap(err)
+	}
+
+	var drift Drift
+	for _, s := range list {
+		switch {
+		case s.Missing:
+			// Nothing to replay, the changes of the migration are reported as extra ones
+		case s.Applied:
+			drift.Applied++
+		default:
+			drift.Pending++
+		}
+	}
+
+	ref := &Migrator{
+		db:      reference,
+		dialect: m.dialect,
+		table:   m.table,
+		set:     m.set,
+		source:  m.source,
+		code:    m.code,
+	}
+	refList, err := ref.Status()
+	if err != nil {
+		return Drift{}, errtrace.Wrap(errors.Join(ErrFailedToDiffSchema, err))
+	}
+	for _, s := range refList {
+		if s.Applied {
+			return Drift{}, errtrace.Wrap(ErrDatabaseNotEmpty)
+		}
+	}
+
+	if drift.Applied > 0 {
+		if _, err := ref.Up(ctx, drift.Applied); err != nil {
+			return Drift{}, errtrace.Wrap(errors.Join(ErrFailedToDiffSchema, err))
+		}
+	}
+
+	want, err := ref.Schema(ctx)
+	if err != nil {
+		return Drift{}, errtrace.Wrap(errors.Join(ErrFailedToDiffSchema, err))
+	}
+	actual, err := m.Schema(ctx)
+	if err != nil {
+		return Drift{}, errtrace.Wrap(errors.Join(ErrFailedToDiffSchema, err))
+	}
+
+	drift.Changes = DiffSchemas(want, actual)
+
+	return drift, nil
+}
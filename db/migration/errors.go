@@ -4,7 +4,25 @@ import "errors"
 
 // Predefined errors.
 var (
-	ErrFailedToApplyMigrations = errors.New("failed to apply migrations")
-	ErrMissedDBConnection      = errors.New("missed db connection")
-	ErrUndefinedDBDriver       = errors.New("undefined db driver")
+	ErrFailedToApplyMigrations     = errors.New("failed to apply migrations")
+	ErrFailedToLoadMigrations      = errors.New("failed to load migrations")
+	ErrFailedToGetMigrationRecords = errors.New("failed to get applied migrations")
+	ErrFailedToPlanMigrations      = errors.New("failed to plan migrations")
+	ErrMissedDBConnection          = errors.New("missed db connection")
+	ErrMissedMigrationsSource      = errors.New("missed migrations source")
+	ErrUndefinedDBDriver           = errors.New("undefined db driver")
+	ErrInvalidDirection            = errors.New("invalid migration direction")
+	ErrInvalidMigrationsLimit      = errors.New("migrations limit must not be negative")
+	ErrNoAppliedMigrations         = errors.New("no applied migrations")
+	ErrFailedToAcquireLock         = errors.New("failed to acquire migrations lock")
+	ErrFailedToReleaseLock         = errors.New("failed to release migrations lock")
+	ErrLockLost                    = errors.New("migrations lock was taken over by another instance")
+	ErrSingleConnectionPool        = errors.New("migrations lock requires at least 2 open connections")
+	ErrFailedToReadSchema          = errors.New("failed to read database schema")
+	ErrFailedToOpenScratchDB       = errors.New("failed to open scratch database")
+	ErrFailedToCloseScratchDB      = errors.New("failed to close scratch database")
+	ErrFailedToVerifyMigrations    = errors.New("failed to verify migrations")
+	ErrFailedToDiffSchema          = errors.New("failed to compare database schema with migrations")
+	ErrDatabaseNotEmpty            = errors.New("database must not have applied migrations")
+	ErrIrreversibleMigration       = errors.New("migration is not reversible")
 )
@@ -0,0 +1,95 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// GoMigrationFunc is the body of a Go migration.
+// It runs inside the migration transaction, q is bound to the same transaction.
+type GoMigrationFunc func(ctx context.Context, tx *sql.Tx, q repository.Querier) error
+
+// GoMigration is a migration written in Go, e.g. a data backfill which needs Go logic.
+// Go migrations are ordered together with the SQL migrations by ID
+// and recorded in the same migrations table, so the ID must follow
+// the same naming as the SQL files, e.g. "20240301120000-split-author-names".
+//
+// A migration without Down is irreversible: rolling it back fails with ErrIrreversibleMigration,
+// unless RecordOnlyDown is set, then only the migration record is removed and the data stays as is.
+type GoMigration struct {
+	ID             string
+	Up             GoMigrationFunc
+	Down           GoMigrationFunc
+	RecordOnlyDown bool // roll back without Down by removing the migration record only
+}
+
+var (
+	goMigrationsMu sync.RWMutex
+	goMigrations   = make(map[string]GoMigration)
+)
+
+// Register makes the Go migration available to all migrators created afterwards.
+// It is intended to be called from init functions of the package containing migrations.
+// It panics if the ID is empty or registered twice, or if Up is nil.
+func Register(m GoMigration) {
+	goMigrationsMu.Lock()
+	defer goMigrationsMu.Unlock()
+
+	if m.ID == "" {
+		panic("migration: Register called with empty ID")
+	}
+	if m.Up == nil {
+		panic("migration: Register called with nil Up for " + m.ID)
+	}
+	if _, dup := goMigrations[m.ID]; dup {
+		panic("migration: Register called twice for " + m.ID)
+	}
+	goMigrations[m.ID] = m
+}
+
+// registered returns a copy of the registered Go migrations.
+func registered() map[string]GoMigration {
+	goMigrationsMu.RLock()
+	defer goMigrationsMu.RUnlock()
+
+	result := make(map[string]GoMigration, len(goMigrations))
+	for id, m := range goMigrations {
+		result[id] = m
+	}
+	return result
+}
+
+// combinedSource merges the SQL migrations with the Go migrations.
+// Go migrations are represented by migrations without queries.
+type combinedSource struct {
+	files migrate.MigrationSource
+	code  map[string]GoMigration
+}
+
+// FindMigrations implements migrate.MigrationSource.
+func (s combinedSource) FindMigrations() ([]*migrate.Migration, error) {
+	migrations, err := s.files.FindMigrations()
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	for _, mg := range migrations {
+		if _, ok := s.code[mg.Id]; ok {
+			return nil, errtrace.Wrap(fmt.Errorf("duplicate migration ID %q: defined both in SQL and in Go", mg.Id))
+		}
+	}
+	for id := range s.code {
+		migrations = append(migrations, &migrate.Migration{Id: id})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Less(migrations[j]) })
+
+	return migrations, nil
+}
@@ -0,0 +1,215 @@
+package migration
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db/dberr"
+)
+
+// Lock settings for the SQLite lock table.
+const (
+	lockPollInterval = 250 * time.Millisecond
+	// The holder refreshes the lock every lockRefreshInterval while the migrations run,
+	// a lock not refreshed for lockStaleAfter is considered abandoned by a crashed instance.
+	lockRefreshInterval = time.Minute
+	lockStaleAfter      = 10 * time.Minute
+)
+
+// UpWithLock applies all pending migrations holding a cross-instance lock,
+// so several replicas booting at once don't race each other.
+// For Postgres a session-level advisory lock is used, for SQLite a lock table.
+// It waits for the lock no longer than wait, zero means until the context is done.
+// The wait doesn't limit the migrations themselves, only the context does.
+func (m *Migrator) UpWithLock(ctx context.Context, wait time.Duration) (int, error) {
+	lockCtx := ctx
+	if wait > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, wait)
+		defer cancel()
+	}
+
+	unlock, err := m.lock(lockCtx)
+	if err != nil {
+		return 0, errtrace.Wrap(err)
+	}
+
+	n, err := m.Up(ctx, 0)
+
+	// Release the lock even if the context is already canceled.
+	if uerr := unlock(context.Background()); uerr != nil {
+		err = errors.Join(err, uerr)
+	}
+
+	return n, errtrace.Wrap(err)
+}
+
+// lock acquires the migrations lock and returns the function to release it.
+func (m *Migrator) lock(ctx context.Context) (func(context.Context) error, error) {
+	if m.dialect == "postgres" {
+		return errtrace.Wrap2(m.lockPostgres(ctx))
+	}
+	return errtrace.Wrap2(m.lockTable(ctx))
+}
+
+// lockPostgres acquires a session-level advisory lock on a dedicated connection.
+// The lock is released automatically if the connection is lost.
+// The migrations need another connection, so a pool of a single connection is rejected
+// instead of waiting for the lock forever.
+func (m *Migrator) lockPostgres(ctx context.Context) (func(context.Context) error, error) {
+	if m.db.Stats().MaxOpenConnections == 1 {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToAcquireLock, ErrSingleConnectionPool))
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(m.table))
+	key := int64(h.Sum64())
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToAcquireLock, err))
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		_ = conn.Close()
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToAcquireLock, err))
+	}
+
+	return func(ctx context.Context) error {
+		defer conn.Close()
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+			return errtrace.Wrap(errors.Join(ErrFailedToReleaseLock, err))
+		}
+		return nil
+	}, nil
+}
+
+// lockTable acquires the lock by inserting the single row into the lock table.
+// It polls until the row can be inserted, taking over locks older than lockStaleAfter.
+// While the lock is held it's refreshed in the background, so long-running migrations
+// are not taken for abandoned.
+func (m *Migrator) lockTable(ctx context.Context) (func(context.Context) error, error) {
+	table := m.table + "_lock"
+
+	owner, err := lockOwner()
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToAcquireLock, err))
+	}
+
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := m.tryLockTable(ctx, table, owner)
+		if err != nil && !dberr.Is(err, dberr.ErrBusy) {
+			return nil, errtrace.Wrap(errors.Join(ErrFailedToAcquireLock, err))
+		}
+		if acquired {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errtrace.Wrap(errors.Join(ErrFailedToAcquireLock, ctx.Err()))
+		case <-ticker.C:
+		}
+	}
+
+	refreshCtx, stopRefresh := context.WithCancel(context.Background())
+	refreshed := make(chan error, 1)
+	go func() {
+		refreshed <- m.refreshLockTable(refreshCtx, table, owner)
+	}()
+
+	return func(ctx context.Context) error {
+		stopRefresh()
+		if err := <-refreshed; err != nil {
+			return errtrace.Wrap(errors.Join(ErrFailedToReleaseLock, err))
+		}
+
+		res, err := m.db.ExecContext(ctx,
+			fmt.Sprintf(`DELETE FROM %s WHERE id = 1 AND owner = ?`, table),
+			owner,
+		)
+		if err != nil {
+			return errtrace.Wrap(errors.Join(ErrFailedToReleaseLock, err))
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return errtrace.Wrap(errors.Join(ErrFailedToReleaseLock, ErrLockLost))
+		}
+		return nil
+	}, nil
+}
+
+// refreshLockTable updates the lock timestamp every lockRefreshInterval until the context is done.
+// It returns ErrLockLost if the lock was taken over by another instance.
+// Failed updates are retried on the next tick, the lock is stale only after lockStaleAfter.
+func (m *Migrator) refreshLockTable(ctx context.Context, table, owner string) error {
+	ticker := time.NewTicker(lockRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		res, err := m.db.ExecContext(ctx,
+			fmt.Sprintf(`UPDATE %s SET locked_at = ? WHERE id = 1 AND owner = ?`, table),
+			time.Now().Unix(), owner,
+		)
+		if err != nil {
+			continue
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return errtrace.Wrap(ErrLockLost)
+		}
+	}
+}
+
+// tryLockTable makes a single attempt to acquire the lock.
+// The database may be busy when several instances start at once, such errors are reported
+// to the caller to retry.
+func (m *Migrator) tryLockTable(ctx context.Context, table, owner string) (bool, error) {
+	if _, err := m.db.ExecContext(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY CHECK (id = 1), owner TEXT NOT NULL, locked_at INTEGER NOT NULL)`,
+		table,
+	)); err != nil {
+		return false, errtrace.Wrap(err)
+	}
+
+	// Remove the lock abandoned by a crashed instance
+	if _, err := m.db.ExecContext(ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE locked_at < ?`, table),
+		time.Now().Add(-lockStaleAfter).Unix(),
+	); err != nil {
+		return false, errtrace.Wrap(err)
+	}
+
+	res, err := m.db.ExecContext(ctx,
+		fmt.Sprintf(`INSERT INTO %s (id, owner, locked_at) VALUES (1, ?, ?) ON CONFLICT (id) DO NOTHING`, table),
+		owner, time.Now().Unix(),
+	)
+	if err != nil {
+		return false, errtrace.Wrap(err)
+	}
+
+	n, err := res.RowsAffected()
+	return n == 1, errtrace.Wrap(err)
+}
+
+// lockOwner returns a random identifier of the lock owner.
+func lockOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errtrace.Wrap(err)
+	}
+	return hex.EncodeToString(b), nil
+}
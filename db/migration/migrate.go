@@ -1,8 +1,8 @@
 package migration
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 
 	"braces.dev/errtrace"
 	migrate "github.com/rubenv/sql-migrate"
@@ -23,33 +23,9 @@ func Down(db *sql.DB, driver, migrationsTable, migrationsDir string) (int, error
 // the directory containing the migration files, and the migration direction.
 // It returns the number of applied migrations and any error encountered.
 func Run(db *sql.DB, driver, migrationsTable, migrationsDir string, direction migrate.MigrationDirection) (int, error) {
-	// Validate input parameters
-	if db == nil {
-		return 0, errtrace.Wrap(ErrMissedDBConnection)
-	}
-	if driver == "" {
-		return 0, errtrace.Wrap(ErrUndefinedDBDriver)
-	}
-	if migrationsTable == "" {
-		migrationsTable = "migrations"
-	}
-	if migrationsDir == "" {
-		migrationsTable = "./db/sql/migrations"
-	}
-
-	// Create migration set
-	m := migrate.MigrationSet{
-		TableName: migrationsTable,
-	}
-	migrations := &migrate.FileMigrationSource{
-		Dir: migrationsDir,
-	}
-
-	// Apply migrations
-	n, err := m.Exec(db, driver, migrations, direction)
+	m, err := NewMigrator(db, driver, migrationsTable, migrationsDir)
 	if err != nil {
-		return 0, errtrace.Wrap(errors.Join(ErrFailedToApplyMigrations, err))
+		return 0, errtrace.Wrap(err)
 	}
-
-	return n, nil
+	return errtrace.Wrap2(m.exec(context.Background(), direction, 0))
 }
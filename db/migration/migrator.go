@@ -0,0 +1,370 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/go-gorp/gorp/v3"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// Migration directions.
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// Migrator runs migrations from a single source against a database.
+type Migrator struct {
+	db      *sql.DB
+	dialect string
+	table   string
+	set     migrate.MigrationSet
+	source  migrate.MigrationSource
+	// code contains the registered Go migrations by ID
+	code map[string]GoMigration
+}
+
+// Status describes the state of a single migration.
+type Status struct {
+	ID        string     `json:"id"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Missing is true if the migration is recorded in the database, but not found in the source.
+	Missing bool `json:"missing,omitempty"`
+	// Go is true for migrations written in Go, see Register.
+	Go bool `json:"go,omitempty"`
+}
+
+// Plan describes a migration which is going to be applied or rolled back.
+type Plan struct {
+	ID        string   `json:"id"`
+	Direction string   `json:"direction"`
+	Queries   []string `json:"queries"`
+	// Go is true for migrations written in Go, they have no queries.
+	Go bool `json:"go,omitempty"`
+}
+
+// NewMigrator creates a new migrator for the migration files in the given directory.
+// The dialect must be one of the sql-migrate dialects, e.g. "sqlite3" or "postgres".
+func NewMigrator(db *sql.DB, dialect, migrationsTable, migrationsDir string) (*Migrator, error) {
+	if migrationsDir == "" {
+		migrationsDir = "./db/sql/migrations"
+	}
+	return errtrace.Wrap2(NewMigratorFS(db, dialect, migrationsTable, os.DirFS(migrationsDir)))
+}
+
+// NewMigratorFS creates a new migrator for the migration files in the root of the given file system,
+// e.g. the embed.FS from the db/sql/migrations package.
+// Go migrations registered with Register are run along with the files.
+// The dialect must be one of the sql-migrate dialects, e.g. "sqlite3" or "postgres".
+func NewMigratorFS(db *sql.DB, dialect, migrationsTable string, migrations fs.FS) (*Migrator, error) {
+	// Validate input parameters
+	if db == nil {
+		return nil, errtrace.Wrap(ErrMissedDBConnection)
+	}
+	if dialect == "" {
+		return nil, errtrace.Wrap(ErrUndefinedDBDriver)
+	}
+	if migrations == nil {
+		return nil, errtrace.Wrap(ErrMissedMigrationsSource)
+	}
+	if migrationsTable == "" {
+		migrationsTable = "migrations"
+	}
+
+	code := registered()
+	return &Migrator{
+		db:      db,
+		dialect: dialect,
+		table:   migrationsTable,
+		set:     migrate.MigrationSet{TableName: migrationsTable},
+		source: combinedSource{
+			files: &migrate.HttpFileSystemMigrationSource{FileSystem: http.FS(migrations)},
+			code:  code,
+		},
+		code: code,
+	}, nil
+}
+
+// Status returns the state of all known migrations ordered by ID.
+// Migrations recorded in the database but missing in the source are reported as well.
+func (m *Migrator) Status() ([]Status, error) {
+	migrations, err := m.source.FindMigrations()
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToLoadMigrations, err))
+	}
+
+	records, err := m.records()
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToGetMigrationRecords, err))
+	}
+
+	applied := make(map[string]time.Time, len(records))
+	for _, r := range records {
+		applied[r.Id] = r.AppliedAt
+	}
+
+	result := make([]Status, 0, len(migrations))
+	for _, mg := range migrations {
+		_, isGo := m.code[mg.Id]
+		s := Status{ID: mg.Id, Go: isGo}
+		if at, ok := applied[mg.Id]; ok {
+			at := at
+			s.Applied = true
+			s.AppliedAt = &at
+			delete(applied, mg.Id)
+		}
+		result = append(result, s)
+	}
+
+	// Records without a matching migration in the source
+	for _, r := range records {
+		if _, ok := applied[r.Id]; ok {
+			at := r.AppliedAt
+			result = append(result, Status{ID: r.Id, Applied: true, AppliedAt: &at, Missing: true})
+		}
+	}
+
+	return result, nil
+}
+
+// Up applies at most max pending migrations, zero means all.
+// It returns the number of applied migrations.
+func (m *Migrator) Up(ctx context.Context, max int) (int, error) {
+	return errtrace.Wrap2(m.exec(ctx, migrate.Up, max))
+}
+
+// Down rolls back at most max applied migrations, zero means all.
+// It returns the number of rolled back migrations.
+func (m *Migrator) Down(ctx context.Context, max int) (int, error) {
+	return errtrace.Wrap2(m.exec(ctx, migrate.Down, max))
+}
+
+// Redo rolls back the last applied migration and applies it again.
+// It returns the ID of the reapplied migration.
+func (m *Migrator) Redo(ctx context.Context) (string, error) {
+	plan, err := m.Plan(DirectionDown, 1)
+	if err != nil {
+		return "", errtrace.Wrap(err)
+	}
+	if len(plan) == 0 {
+		return "", errtrace.Wrap(ErrNoAppliedMigrations)
+	}
+
+	if _, err := m.Down(ctx, 1); err != nil {
+		return "", errtrace.Wrap(err)
+	}
+	if _, err := m.Up(ctx, 1); err != nil {
+		return "", errtrace.Wrap(err)
+	}
+
+	return plan[0].ID, nil
+}
+
+// Plan returns the migrations with their queries which would be executed
+// in the given direction, without executing them or writing anything. Zero max means all.
+func (m *Migrator) Plan(direction string, max int) ([]Plan, error) {
+	dir, err := parseDirection(direction)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	if max < 0 {
+		return nil, errtrace.Wrap(ErrInvalidMigrationsLimit)
+	}
+
+	// sql-migrate creates the migrations table to plan, so without the table
+	// the plan is built from the source: nothing is applied yet.
+	exists, err := m.hasTable()
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToPlanMigrations, err))
+	}
+	if !exists {
+		return errtrace.Wrap2(m.planFromScratch(direction, max))
+	}
+
+	planned, _, err := m.set.PlanMigration(m.db, m.dialect, m.source, dir, max)
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToPlanMigrations, err))
+	}
+
+	result := make([]Plan, 0, len(planned))
+	for _, p := range planned {
+		_, isGo := m.code[p.Id]
+		result = append(result, Plan{ID: p.Id, Direction: direction, Queries: p.Queries, Go: isGo})
+	}
+
+	return result, nil
+}
+
+// planFromScratch returns the plan for the database without applied migrations.
+func (m *Migrator) planFromScratch(direction string, max int) ([]Plan, error) {
+	if direction == DirectionDown {
+		return []Plan{}, nil
+	}
+
+	migrations, err := m.source.FindMigrations()
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToLoadMigrations, err))
+	}
+	if max > 0 && max < len(migrations) {
+		migrations = migrations[:max]
+	}
+
+	result := make([]Plan, 0, len(migrations))
+	for _, mg := range migrations {
+		_, isGo := m.code[mg.Id]
+		result = append(result, Plan{ID: mg.Id, Direction: direction, Queries: mg.Up, Go: isGo})
+	}
+	return result, nil
+}
+
+// records returns the applied migrations. Unlike sql-migrate, it doesn't create
+// the migrations table, so the read-only commands never write to the database.
+func (m *Migrator) records() ([]*migrate.MigrationRecord, error) {
+	exists, err := m.hasTable()
+	if err != nil || !exists {
+		return nil, errtrace.Wrap(err)
+	}
+	return errtrace.Wrap2(m.set.GetMigrationRecords(m.db, m.dialect))
+}
+
+// hasTable reports whether the migrations table exists.
+func (m *Migrator) hasTable() (bool, error) {
+	query := `SELECT count(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if m.dialect == "postgres" {
+		query = `SELECT to_regclass($1) IS NOT NULL`
+	}
+
+	var exists bool
+	if err := m.db.QueryRow(query, m.table).Scan(&exists); err != nil {
+		return false, errtrace.Wrap(err)
+	}
+	return exists, nil
+}
+
+// PlanRedo returns the plans to roll back the last applied migration and to apply it again,
+// without executing them.
+func (m *Migrator) PlanRedo() ([]Plan, error) {
+	down, err := m.Plan(DirectionDown, 1)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	if len(down) == 0 {
+		return nil, errtrace.Wrap(ErrNoAppliedMigrations)
+	}
+
+	migrations, err := m.source.FindMigrations()
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToLoadMigrations, err))
+	}
+	for _, mg := range migrations {
+		if mg.Id == down[0].ID {
+			return append(down, Plan{ID: mg.Id, Direction: DirectionUp, Queries: mg.Up, Go: down[0].Go}), nil
+		}
+	}
+
+	return nil, errtrace.Wrap(errors.Join(ErrFailedToLoadMigrations, errors.New(down[0].ID)))
+}
+
+// exec executes at most max migrations in the given direction.
+func (m *Migrator) exec(ctx context.Context, dir migrate.MigrationDirection, max int) (int, error) {
+	if max < 0 {
+		return 0, errtrace.Wrap(ErrInvalidMigrationsLimit)
+	}
+
+	planned, dbMap, err := m.set.PlanMigration(m.db, m.dialect, m.source, dir, max)
+	if err != nil {
+		return 0, errtrace.Wrap(errors.Join(ErrFailedToPlanMigrations, err))
+	}
+
+	for n, p := range planned {
+		if err := m.apply(ctx, dir, p, dbMap.Dialect); err != nil {
+			return n, errtrace.Wrap(errors.Join(ErrFailedToApplyMigrations, fmt.Errorf("%s: %w", p.Id, err)))
+		}
+	}
+
+	return len(planned), nil
+}
+
+// apply runs a single migration and records it in the migrations table in the same transaction.
+// SQL migrations with the notransaction option are run without a transaction, as sql-migrate does.
+func (m *Migrator) apply(ctx context.Context, dir migrate.MigrationDirection, p *migrate.PlannedMigration, d gorp.Dialect) error {
+	table := d.QuotedTableForQuery(m.set.SchemaName, m.table)
+	record := fmt.Sprintf("DELETE FROM %s WHERE id = %s", table, d.BindVar(0))
+	args := []interface{}{p.Id}
+	if dir == migrate.Up {
+		record = fmt.Sprintf("INSERT INTO %s (id, applied_at) VALUES (%s, %s)", table, d.BindVar(0), d.BindVar(1))
+		args = append(args, time.Now())
+	}
+
+	gm, isGo := m.code[p.Id]
+	if p.DisableTransaction && !isGo {
+		for _, q := range p.Queries {
+			if _, err := m.db.ExecContext(ctx, trimStatement(q)); err != nil {
+				return errtrace.Wrap(err)
+			}
+		}
+		_, err := m.db.ExecContext(ctx, record, args...)
+		return errtrace.Wrap(err)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if isGo {
+		fn := gm.Up
+		if dir == migrate.Down {
+			fn = gm.Down
+			if fn == nil && !gm.RecordOnlyDown {
+				return errtrace.Wrap(ErrIrreversibleMigration)
+			}
+		}
+		if fn != nil {
+			if err := fn(ctx, tx, db.NewQuerier(db.Dialect(m.dialect), tx)); err != nil {
+				return errtrace.Wrap(err)
+			}
+		}
+	} else {
+		for _, q := range p.Queries {
+			if _, err := tx.ExecContext(ctx, trimStatement(q)); err != nil {
+				return errtrace.Wrap(err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	return errtrace.Wrap(tx.Commit())
+}
+
+// trimStatement removes the trailing semicolon and whitespace from the statement.
+func trimStatement(q string) string {
+	return strings.TrimSuffix(strings.TrimSpace(q), ";")
+}
+
+// parseDirection converts the direction name to the sql-migrate direction.
+func parseDirection(direction string) (migrate.MigrationDirection, error) {
+	switch direction {
+	case DirectionUp:
+		return migrate.Up, nil
+	case DirectionDown:
+		return migrate.Down, nil
+	}
+	return 0, errtrace.Wrap(ErrInvalidDirection)
+}
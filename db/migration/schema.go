@@ -0,0 +1,464 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"braces.dev/errtrace"
+)
+
+// Schema is a dialect-neutral description of the database schema.
+type Schema struct {
+	Tables []Table `json:"tables"`
+}
+
+// Table describes a database table.
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+	Indexes []Index  `json:"indexes,omitempty"`
+}
+
+// Column describes a table column.
+type Column struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	NotNull    bool   `json:"not_null"`
+	Default    string `json:"default,omitempty"`
+	PrimaryKey bool   `json:"primary_key"`
+}
+
+// Index describes a table index.
+type Index struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Columns []string `json:"columns"`
+	// Constraint is true for indexes created implicitly by PRIMARY KEY or UNIQUE constraints.
+	Constraint bool `json:"constraint,omitempty"`
+}
+
+// String returns the column definition.
+func (c Column) String() string {
+	var b strings.Builder
+	b.WriteString(c.Name + " " + c.Type)
+	if c.PrimaryKey {
+		b.WriteString(" PRIMARY KEY")
+	}
+	if c.NotNull {
+		b.WriteString(" NOT NULL")
+	}
+	if c.Default != "" {
+		b.WriteString(" DEFAULT " + c.Default)
+	}
+	return b.String()
+}
+
+// String returns the index definition.
+func (i Index) String() string {
+	kind := "INDEX"
+	if i.Unique {
+		kind = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("%s %s (%s)", kind, i.Name, strings.Join(i.Columns, ", "))
+}
+
+// Table returns the table with the given name.
+func (s Schema) Table(name string) (Table, bool) {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Table{}, false
+}
+
+// ReadSchema introspects the schema of the database.
+// Tables with the given names are excluded, e.g. the migrations table.
+// The dialect must be either "sqlite3" or "postgres".
+func ReadSchema(ctx context.Context, db *sql.DB, dialect string, exclude ...string) (Schema, error) {
+	var (
+		tables []string
+		err    error
+	)
+	switch dialect {
+	case "postgres":
+		tables, err = queryStrings(ctx, db, `SELECT table_name FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name`)
+	default:
+		tables, err = queryStrings(ctx, db, `SELECT name FROM sqlite_master
+			WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	}
+	if err != nil {
+		return Schema{}, errtrace.Wrap(errors.Join(ErrFailedToReadSchema, err))
+	}
+
+	skip := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		skip[name] = true
+	}
+
+	schema := Schema{Tables: make([]Table, 0, len(tables))}
+	for _, name := range tables {
+		if skip[name] {
+			continue
+		}
+
+		t := Table{Name: name}
+		switch dialect {
+		case "postgres":
+			t.Columns, err = readPostgresColumns(ctx, db, name)
+			if err == nil {
+				t.Indexes, err = readPostgresIndexes(ctx, db, name)
+			}
+		default:
+			t.Columns, err = readSQLiteColumns(ctx, db, name)
+			if err == nil {
+				t.Indexes, err = readSQLiteIndexes(ctx, db, name)
+			}
+		}
+		if err != nil {
+			return Schema{}, errtrace.Wrap(errors.Join(ErrFailedToReadSchema, err))
+		}
+
+		schema.Tables = append(schema.Tables, t)
+	}
+
+	return schema, nil
+}
+
+// readSQLiteColumns returns the columns of the SQLite table in the definition order.
+func readSQLiteColumns(ctx context.Context, db *sql.DB, table string) ([]Column, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT name, type, "notnull", COALESCE(dflt_value, ''), pk FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var (
+			c           Column
+			notNull, pk int64
+		)
+		if err := rows.Scan(&c.Name, &c.Type, &notNull, &c.Default, &pk); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		c.Type = strings.ToUpper(c.Type)
+		c.NotNull = notNull != 0
+		c.PrimaryKey = pk != 0
+		columns = append(columns, c)
+	}
+
+	return columns, errtrace.Wrap(rows.Err())
+}
+
+// readSQLiteIndexes returns the indexes of the SQLite table ordered by name.
+func readSQLiteIndexes(ctx context.Context, db *sql.DB, table string) ([]Index, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name, "unique", origin <> 'c' FROM pragma_index_list(?) ORDER BY name`, table)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var (
+			idx                Index
+			unique, constraint int64
+		)
+		if err := rows.Scan(&idx.Name, &unique, &constraint); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		idx.Unique = unique != 0
+		idx.Constraint = constraint != 0
+		indexes = append(indexes, idx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	_ = rows.Close()
+
+	for i := range indexes {
+		indexes[i].Columns, err = queryStrings(ctx, db,
+			`SELECT COALESCE(name, '') FROM pragma_index_info(?) ORDER BY seqno`, indexes[i].Name)
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+	}
+
+	return indexes, nil
+}
+
+// readPostgresColumns returns the columns of the Postgres table in the definition order.
+func readPostgresColumns(ctx context.Context, db *sql.DB, table string) ([]Column, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.column_name, UPPER(c.data_type), c.is_nullable = 'NO', COALESCE(c.column_default, ''),
+			EXISTS (
+				SELECT 1 FROM information_schema.table_constraints tc
+				JOIN information_schema.key_column_usage kcu
+					ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+				WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+					AND tc.table_name = c.table_name AND kcu.column_name = c.column_name
+			)
+		FROM information_schema.columns c
+		WHERE c.table_schema = current_schema() AND c.table_name = $1
+		ORDER BY c.ordinal_position`, table)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var c Column
+		if err := rows.Scan(&c.Name, &c.Type, &c.NotNull, &c.Default, &c.PrimaryKey); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		columns = append(columns, c)
+	}
+
+	return columns, errtrace.Wrap(rows.Err())
+}
+
+// readPostgresIndexes returns the indexes of the Postgres table ordered by name.
+func readPostgresIndexes(ctx context.Context, db *sql.DB, table string) ([]Index, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT i.relname, ix.indisunique,
+			ix.indisprimary OR EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid),
+			array_to_string(array_agg(a.attname ORDER BY k.n), ',')
+		FROM pg_class t
+		JOIN pg_namespace ns ON ns.oid = t.relnamespace
+		JOIN pg_index ix ON ix.indrelid = t.oid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		CROSS JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, n)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE ns.nspname = current_schema() AND t.relname = $1
+		GROUP BY i.relname, ix.indisunique, ix.indisprimary, ix.indexrelid
+		ORDER BY i.relname`, table)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var (
+			idx     Index
+			columns string
+		)
+		if err := rows.Scan(&idx.Name, &idx.Unique, &idx.Constraint, &columns); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		idx.Columns = strings.Split(columns, ",")
+		indexes = append(indexes, idx)
+	}
+
+	return indexes, errtrace.Wrap(rows.Err())
+}
+
+// SQL renders the schema as canonical CREATE TABLE and CREATE INDEX statements,
+// tables are ordered by name, so the output is stable and can be committed.
+// Indexes created by constraints are rendered as table constraints.
+func (s Schema) SQL() string {
+	tables := append([]Table(nil), s.Tables...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	var b strings.Builder
+	for i, t := range tables {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		var pk []string
+		for _, c := range t.Columns {
+			if c.PrimaryKey {
+				pk = append(pk, c.Name)
+			}
+		}
+
+		defs := make([]string, 0, len(t.Columns))
+		for _, c := range t.Columns {
+			// Composite primary key is rendered as a table constraint
+			if len(pk) > 1 {
+				c.PrimaryKey = false
+			}
+			defs = append(defs, c.String())
+		}
+		if len(pk) > 1 {
+			defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pk, ", ")))
+		}
+
+		var indexes []Index
+		for _, idx := range t.Indexes {
+			switch {
+			case !idx.Constraint:
+				indexes = append(indexes, idx)
+			case idx.Unique && !isPrimaryKeyIndex(idx, pk):
+				defs = append(defs, fmt.Sprintf("UNIQUE (%s)", strings.Join(idx.Columns, ", ")))
+			}
+		}
+
+		fmt.Fprintf(&b, "CREATE TABLE %s (\n  %s\n);\n", t.Name, strings.Join(defs, ",\n  "))
+		for _, idx := range indexes {
+			unique := ""
+			if idx.Unique {
+				unique = "UNIQUE "
+			}
+			fmt.Fprintf(&b, "CREATE %sINDEX %s ON %s (%s);\n", unique, idx.Name, t.Name, strings.Join(idx.Columns, ", "))
+		}
+	}
+
+	return b.String()
+}
+
+// isPrimaryKeyIndex reports whether the index covers exactly the primary key columns.
+func isPrimaryKeyIndex(idx Index, pk []string) bool {
+	if len(idx.Columns) != len(pk) {
+		return false
+	}
+	for i := range pk {
+		if idx.Columns[i] != pk[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// queryStrings runs the query and returns the first column of all rows.
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		result = append(result, s)
+	}
+
+	return result, errtrace.Wrap(rows.Err())
+}
+
+// Change describes a single difference between two schemas.
+type Change struct {
+	// Kind is one of: missing_table, extra_table, missing_column, extra_column,
+	// changed_column, missing_index, extra_index, changed_index.
+	Kind   string `json:"kind"`
+	Table  string `json:"table"`
+	Name   string `json:"name,omitempty"`
+	Want   string `json:"want,omitempty"`
+	Actual string `json:"actual,omitempty"`
+}
+
+// String returns the human-readable description of the change.
+func (c Change) String() string {
+	switch c.Kind {
+	case "missing_table":
+		return fmt.Sprintf("- table %s", c.Table)
+	case "extra_table":
+		return fmt.Sprintf("+ table %s", c.Table)
+	case "missing_column", "missing_index":
+		return fmt.Sprintf("- %s.%s: %s", c.Table, c.Name, c.Want)
+	case "extra_column", "extra_index":
+		return fmt.Sprintf("+ %s.%s: %s", c.Table, c.Name, c.Actual)
+	default:
+		return fmt.Sprintf("~ %s.%s: %s => %s", c.Table, c.Name, c.Want, c.Actual)
+	}
+}
+
+// DiffSchemas compares the actual schema with the wanted one.
+// "missing" changes are present in want only, "extra" changes are present in actual only.
+// The result is ordered by table and object name; empty result means the schemas are equal.
+func DiffSchemas(want, actual Schema) []Change {
+	var changes []Change
+
+	for _, wt := range want.Tables {
+		at, ok := actual.Table(wt.Name)
+		if !ok {
+			changes = append(changes, Change{Kind: "missing_table", Table: wt.Name})
+			continue
+		}
+		changes = append(changes, diffColumns(wt, at)...)
+		changes = append(changes, diffIndexes(wt, at)...)
+	}
+	for _, at := range actual.Tables {
+		if _, ok := want.Table(at.Name); !ok {
+			changes = append(changes, Change{Kind: "extra_table", Table: at.Name})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Table != changes[j].Table {
+			return changes[i].Table < changes[j].Table
+		}
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
+
+// diffColumns compares the columns of the same table.
+func diffColumns(want, actual Table) []Change {
+	var changes []Change
+
+	actualCols := make(map[string]Column, len(actual.Columns))
+	for _, c := range actual.Columns {
+		actualCols[c.Name] = c
+	}
+
+	for _, wc := range want.Columns {
+		ac, ok := actualCols[wc.Name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: "missing_column", Table: want.Name, Name: wc.Name, Want: wc.String()})
+		case wc != ac:
+			changes = append(changes, Change{Kind: "changed_column", Table: want.Name, Name: wc.Name, Want: wc.String(), Actual: ac.String()})
+		}
+		delete(actualCols, wc.Name)
+	}
+	for _, ac := range actual.Columns {
+		if _, ok := actualCols[ac.Name]; ok {
+			changes = append(changes, Change{Kind: "extra_column", Table: want.Name, Name: ac.Name, Actual: ac.String()})
+		}
+	}
+
+	return changes
+}
+
+// diffIndexes compares the indexes of the same table.
+func diffIndexes(want, actual Table) []Change {
+	var changes []Change
+
+	actualIdx := make(map[string]Index, len(actual.Indexes))
+	for _, i := range actual.Indexes {
+		actualIdx[i.Name] = i
+	}
+
+	for _, wi := range want.Indexes {
+		ai, ok := actualIdx[wi.Name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: "missing_index", Table: want.Name, Name: wi.Name, Want: wi.String()})
+		case wi.String() != ai.String():
+			changes = append(changes, Change{Kind: "changed_index", Table: want.Name, Name: wi.Name, Want: wi.String(), Actual: ai.String()})
+		}
+		delete(actualIdx, wi.Name)
+	}
+	for _, ai := range actual.Indexes {
+		if _, ok := actualIdx[ai.Name]; ok {
+			changes = append(changes, Change{Kind: "extra_index", Table: want.Name, Name: ai.Name, Actual: ai.String()})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+)
+
+// ScratchDB is a throwaway database to replay migrations against,
+// e.g. to verify them or to build the reference schema.
+type ScratchDB struct {
+	DB      *sql.DB
+	Dialect string
+	drop    func(context.Context) error
+}
+
+// OpenScratch opens an empty scratch database of the same dialect as the given database URL.
+// For SQLite (libSQL) an in-memory database is used and the URL is only used to choose the dialect.
+// For Postgres a temporary schema is created in the database the URL points to
+// and dropped on Close.
+func OpenScratch(ctx context.Context, dbURL string) (*ScratchDB, error) {
+	dialect, err := db.DialectFromURL(dbURL)
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToOpenScratchDB, err))
+	}
+
+	if dialect != db.DialectPostgres {
+		conn, _, err := db.Open(ctx, ":memory:", db.Options{MaxOpenConns: 1, MaxIdleConns: 1})
+		if err != nil {
+			return nil, errtrace.Wrap(errors.Join(ErrFailedToOpenScratchDB, err))
+		}
+		return &ScratchDB{DB: conn, Dialect: string(dialect)}, nil
+	}
+
+	return errtrace.Wrap2(openPostgresScratch(ctx, dbURL))
+}
+
+// openPostgresScratch creates a temporary schema and opens a connection
+// with the search path set to it, so all unqualified objects are created there.
+func openPostgresScratch(ctx context.Context, dbURL string) (*ScratchDB, error) {
+	suffix, err := lockOwner()
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToOpenScratchDB, err))
+	}
+	schema := "migrate_scratch_" + suffix[:12]
+
+	admin, _, err := db.Open(ctx, dbURL, db.Options{MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToOpenScratchDB, err))
+	}
+	if _, err := admin.ExecContext(ctx, fmt.Sprintf(`CREATE SCHEMA %s`, schema)); err != nil {
+		_ = admin.Close()
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToOpenScratchDB, err))
+	}
+
+	drop := func(ctx context.Context) error {
+		defer admin.Close()
+		if _, err := admin.ExecContext(ctx, fmt.Sprintf(`DROP SCHEMA %s CASCADE`, schema)); err != nil {
+			return errtrace.Wrap(errors.Join(ErrFailedToCloseScratchDB, err))
+		}
+		return nil
+	}
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToOpenScratchDB, err, drop(ctx)))
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	conn, _, err := db.Open(ctx, u.String(), db.Options{MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToOpenScratchDB, err, drop(ctx)))
+	}
+
+	return &ScratchDB{DB: conn, Dialect: string(db.DialectPostgres), drop: drop}, nil
+}
+
+// Close closes the scratch database and drops the temporary schema, if any.
+func (s *ScratchDB) Close(ctx context.Context) error {
+	err := s.DB.Close()
+	if s.drop != nil {
+		err = errors.Join(err, s.drop(ctx))
+	}
+	return errtrace.Wrap(err)
+}
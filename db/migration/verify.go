@@ -0,0 +1,120 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"braces.dev/errtrace"
+)
+
+// Verification steps.
+const (
+	StepDown = "down" // the schema after rolling back must match the schema before applying
+	StepRedo = "redo" // the schema after applying again must match the schema after the first apply
+)
+
+// Verification is the result of the reversibility check of a single migration.
+type Verification struct {
+	ID string `json:"id"`
+	// Step is the step which failed, empty if the migration is reversible.
+	Step string `json:"step,omitempty"`
+	// Changes are the differences between the expected and the actual schema after the failed step.
+	Changes []Change `json:"changes,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// OK reports whether the migration is reversible.
+func (v Verification) OK() bool {
+	return v.Step == ""
+}
+
+// Verify checks that every migration can be rolled back and applied again.
+// Migrations are applied one by one running up, down and up again,
+// and the schema is compared after each step.
+// It must be run against an empty scratch database, see OpenScratch.
+//
+// It stops at the first irreversible migration and returns ErrIrreversibleMigration
+// along with the results of all checked migrations.
+func (m *Migrator) Verify(ctx context.Context) ([]Verification, error) {
+	list, err := m.Status()
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	for _, s := range list {
+		if s.Applied {
+			return nil, errtrace.Wrap(ErrDatabaseNotEmpty)
+		}
+	}
+
+	result := make([]Verification, 0, len(list))
+	for _, s := range list {
+		v, err := m.verifyNext(ctx, s.ID)
+		if err != nil {
+			return result, errtrace.Wrap(err)
+		}
+		result = append(result, v)
+		if !v.OK() {
+			return result, errtrace.Wrap(fmt.Errorf("%w: %s", ErrIrreversibleMigration, s.ID))
+		}
+	}
+
+	return result, nil
+}
+
+// verifyNext runs up, down and up again for the next pending migration.
+// Errors of the migration itself are reported in the result,
+// the returned error means the check couldn't be performed.
+func (m *Migrator) verifyNext(ctx context.Context, id string) (Verification, error) {
+	v := Verification{ID: id}
+
+	before, err := m.schema(ctx)
+	if err != nil {
+		return v, errtrace.Wrap(err)
+	}
+	if _, err := m.Up(ctx, 1); err != nil {
+		return v, errtrace.Wrap(err)
+	}
+	applied, err := m.schema(ctx)
+	if err != nil {
+		return v, errtrace.Wrap(err)
+	}
+
+	// Roll back: the schema must be restored to the state before the migration
+	if _, err := m.Down(ctx, 1); err != nil {
+		v.Step, v.Error = StepDown, err.Error()
+		return v, nil
+	}
+	reverted, err := m.schema(ctx)
+	if err != nil {
+		return v, errtrace.Wrap(err)
+	}
+	if v.Changes = DiffSchemas(before, reverted); len(v.Changes) > 0 {
+		v.Step = StepDown
+		return v, nil
+	}
+
+	// Apply again: the migration must not depend on leftovers of the first run
+	if _, err := m.Up(ctx, 1); err != nil {
+		v.Step, v.Error = StepRedo, err.Error()
+		return v, nil
+	}
+	reapplied, err := m.schema(ctx)
+	if err != nil {
+		return v, errtrace.Wrap(err)
+	}
+	if v.Changes = DiffSchemas(applied, reapplied); len(v.Changes) > 0 {
+		v.Step = StepRedo
+	}
+
+	return v, nil
+}
+
+// schema returns the current schema for the verification.
+func (m *Migrator) schema(ctx context.Context) (Schema, error) {
+	s, err := m.Schema(ctx)
+	if err != nil {
+		return Schema{}, errtrace.Wrap(errors.Join(ErrFailedToVerifyMigrations, err))
+	}
+	return s, nil
+}
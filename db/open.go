@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"braces.dev/errtrace"
+)
+
+// Dialect is the SQL dialect of the database.
+// The values match the dialect names used by the migration tool.
+type Dialect string
+
+// Supported dialects.
+const (
+	DialectSQLite   Dialect = "sqlite3"
+	DialectPostgres Dialect = "postgres"
+)
+
+// Driver names used to open the database connection.
+// The drivers must be registered by importing the corresponding driver package:
+// github.com/lib/pq for postgres and github.com/tursodatabase/go-libsql for libsql.
+const (
+	driverLibSQL   = "libsql"
+	driverPostgres = "postgres"
+)
+
+// Options defines the connection pool and connection check settings used by Open and InitDBContext.
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration // zero means connections are reused forever
+	ConnMaxIdleTime time.Duration // zero means idle connections are not closed due to idle time
+	// ConnectTimeout is how long to retry the connection check while the database is unreachable,
+	// zero means a single attempt.
+	ConnectTimeout time.Duration
+	// OnConnectRetry is called after each failed connection check before the retry, e.g. to log it.
+	OnConnectRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Open opens a database connection choosing the driver by the URL scheme:
+//   - postgres://, postgresql:// - PostgreSQL
+//   - libsql://, http://, https:// - remote libSQL (Turso, sqld)
+//   - file:, :memory: - local SQLite file or in-memory database via libSQL
+//
+// It returns the database connection and the dialect to be used for migrations.
+// The connection check is retried according to opts.ConnectTimeout, see InitDBContext.
+// The driver for the chosen scheme must be registered, otherwise ErrDriverNotRegistered is returned.
+func Open(ctx context.Context, dbURL string, opts Options) (*sql.DB, Dialect, error) {
+	driver, dialect, dsn, err := parseURL(dbURL)
+	if err != nil {
+		return nil, "", errtrace.Wrap(err)
+	}
+	if !slices.Contains(sql.Drivers(), driver) {
+		return nil, "", errtrace.Wrap(errors.Join(ErrDriverNotRegistered, errors.New(driver)))
+	}
+
+	// Each connection to an in-memory database gets its own database,
+	// and SQLite allows only one writer at a time, so use a single connection.
+	if isLocalSQLite(dsn) {
+		opts.MaxOpenConns = 1
+		opts.MaxIdleConns = 1
+	}
+
+	db, err := InitDBContext(ctx, driver, dsn, opts)
+	if err != nil {
+		return nil, "", errtrace.Wrap(err)
+	}
+
+	return db, dialect, nil
+}
+
+// DialectFromURL returns the SQL dialect for the given database URL.
+// It can be used to validate the URL without opening a connection.
+func DialectFromURL(dbURL string) (Dialect, error) {
+	_, dialect, _, err := parseURL(dbURL)
+	return dialect, errtrace.Wrap(err)
+}
+
+// parseURL returns the driver name, the dialect and the data source name for the given database URL.
+func parseURL(dbURL string) (driver string, dialect Dialect, dsn string, err error) {
+	if dbURL == "" {
+		return "", "", "", errtrace.Wrap(ErrEmptyDBConnString)
+	}
+	if strings.HasPrefix(dbURL, ":memory:") {
+		return driverLibSQL, DialectSQLite, dbURL, nil
+	}
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return "", "", "", errtrace.Wrap(errors.Join(ErrInvalidDBURL, err))
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "postgres", "postgresql":
+		return driverPostgres, DialectPostgres, dbURL, nil
+	case "libsql", "http", "https", "file":
+		return driverLibSQL, DialectSQLite, dbURL, nil
+	}
+
+	return "", "", "", errtrace.Wrap(errors.Join(ErrUnsupportedDBScheme, errors.New(u.Scheme)))
+}
+
+// isLocalSQLite reports whether the data source name points to a local SQLite database.
+func isLocalSQLite(dsn string) bool {
+	return strings.HasPrefix(dsn, ":memory:") || strings.HasPrefix(dsn, "file:")
+}
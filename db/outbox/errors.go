@@ -0,0 +1,15 @@
+package outbox
+
+import "errors"
+
+// Predefined errors.
+var (
+	ErrMissedDBConnection      = errors.New("missed db connection")
+	ErrMissedPublisher         = errors.New("missed publisher")
+	ErrFailedToMarshalPayload  = errors.New("failed to marshal task payload")
+	ErrFailedToEnqueueMessage  = errors.New("failed to enqueue outbox message")
+	ErrFailedToPublishMessage  = errors.New("failed to publish outbox message")
+	ErrFailedToFetchMessages   = errors.New("failed to fetch pending outbox messages")
+	ErrFailedToUpdateMessage   = errors.New("failed to update outbox message")
+	ErrFailedToCleanupMessages = errors.New("failed to delete published outbox messages")
+)
@@ -0,0 +1,115 @@
+// Package outbox implements the transactional outbox of the background tasks.
+//
+// A task is written to the outbox table in the same transaction as the business change,
+// so it's enqueued if and only if the change is committed. The Relay publishes the pending
+// messages to the task queue afterwards and retries them while the broker is unavailable.
+//
+// Delivery is at-least-once: a message is published again if the relay stops between
+// publishing and marking it published. The dedupe key of the message is used as the task ID
+// in the queue, so such duplicates are dropped by the broker while the task is retained there.
+//
+//	err := db.RunInTx(ctx, conn, db.TxOptions{Dialect: dialect}, func(ctx context.Context, q repository.Querier) error {
+//		if err := q.UpdateAuthor(ctx, arg); err != nil {
+//			return err
+//		}
+//		return outbox.Enqueue(ctx, q, "author_updated", payload, "")
+//	})
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/dberr"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+)
+
+// Enqueue writes the task to the outbox with the repository,
+// pass the repository of the transaction to make it atomic with the other changes.
+// The payload is marshaled to JSON. A message with the same dedupe key is enqueued only once,
+// an empty key is replaced with a random one.
+func Enqueue(ctx context.Context, q repository.Querier, taskName string, payload any, dedupeKey string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errtrace.Wrap(errors.Join(ErrFailedToMarshalPayload, err))
+	}
+	if dedupeKey == "" {
+		if dedupeKey, err = randomKey(); err != nil {
+			return errtrace.Wrap(errors.Join(ErrFailedToEnqueueMessage, err))
+		}
+	}
+
+	now := now()
+	_, err = q.CreateOutboxMessage(ctx, repository.CreateOutboxMessageParams{
+		TaskName:      taskName,
+		Payload:       string(data),
+		DedupeKey:     dedupeKey,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	})
+	if err != nil && !dberr.Is(err, dberr.ErrNotFound) { // no rows means a duplicate
+		return errtrace.Wrap(errors.Join(ErrFailedToEnqueueMessage, err))
+	}
+
+	return nil
+}
+
+// Enqueuer writes the tasks to the outbox instead of the task queue.
+// It has the same EnqueueTask method as asyncer.Enqueuer, so it can be passed
+// to mailer.NewEnqueuer and other task producers.
+type Enqueuer struct {
+	db      *sql.DB
+	dialect db.Dialect
+}
+
+// NewEnqueuer creates a new outbox enqueuer.
+// The tasks are written in the transaction carried by the context, see db.RunInTx,
+// or directly to the given primary database without one.
+func NewEnqueuer(conn *sql.DB, dialect db.Dialect) (*Enqueuer, error) {
+	if conn == nil {
+		return nil, errtrace.Wrap(ErrMissedDBConnection)
+	}
+	return &Enqueuer{db: conn, dialect: dialect}, nil
+}
+
+// EnqueueTask writes the task to the outbox, see Enqueue.
+// The dedupe key is taken from the context, see WithDedupeKey.
+func (e *Enqueuer) EnqueueTask(ctx context.Context, taskName string, payload any) error {
+	var conn repository.DBTX = e.db
+	if tx, ok := db.TxFromContext(ctx); ok {
+		conn = tx
+	}
+	key, _ := ctx.Value(dedupeKey{}).(string)
+	return errtrace.Wrap(Enqueue(ctx, db.NewQuerier(e.dialect, conn), taskName, payload, key))
+}
+
+// dedupeKey is the context key of the dedupe key of the next enqueued task.
+type dedupeKey struct{}
+
+// WithDedupeKey returns a copy of the context with the dedupe key used by Enqueuer.EnqueueTask,
+// e.g. to send a single welcome email even if the signup request is retried.
+func WithDedupeKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, dedupeKey{}, key)
+}
+
+// now returns the current time in UTC truncated to seconds,
+// SQLite compares the stored times as text.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// randomKey returns a random dedupe key.
+func randomKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errtrace.Wrap(err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,79 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	"github.com/hibiken/asynq"
+)
+
+// Publisher publishes the outbox messages to the task queue.
+type Publisher interface {
+	Publish(ctx context.Context, m repository.Outbox) error
+}
+
+// Default settings of the asynq publisher, the same as the ones of asyncer.Enqueuer.
+const (
+	DefaultQueue     = "default"
+	DefaultMaxRetry  = 3
+	DefaultDeadline  = time.Minute
+	DefaultRetention = 24 * time.Hour
+)
+
+// PublisherOptions defines the settings of the tasks published to asynq.
+type PublisherOptions struct {
+	Queue    string        // Default: "default".
+	MaxRetry int           // Default: 3.
+	Deadline time.Duration // Time limit of the task processing. Default: 1m.
+	// Retention keeps the processed task in the queue, duplicates of the message
+	// published within this period are dropped. Default: 24h.
+	Retention time.Duration
+}
+
+// AsynqPublisher publishes the outbox messages as asynq tasks,
+// the payload is passed as is, so the tasks are handled by the usual asyncer handlers.
+type AsynqPublisher struct {
+	client *asynq.Client
+	opts   PublisherOptions
+}
+
+// NewAsynqPublisher creates a new publisher with the asynq client,
+// e.g. the one created with asyncer.NewClient.
+func NewAsynqPublisher(client *asynq.Client, opts PublisherOptions) (*AsynqPublisher, error) {
+	if client == nil {
+		return nil, errtrace.Wrap(ErrMissedPublisher)
+	}
+	if opts.Queue == "" {
+		opts.Queue = DefaultQueue
+	}
+	if opts.MaxRetry <= 0 {
+		opts.MaxRetry = DefaultMaxRetry
+	}
+	if opts.Deadline <= 0 {
+		opts.Deadline = DefaultDeadline
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	return &AsynqPublisher{client: client, opts: opts}, nil
+}
+
+// Publish implements Publisher.
+// A task with the same ID already in the queue means the message was published before.
+func (p *AsynqPublisher) Publish(ctx context.Context, m repository.Outbox) error {
+	_, err := p.client.EnqueueContext(ctx,
+		asynq.NewTask(m.TaskName, []byte(m.Payload)),
+		asynq.TaskID("outbox:"+m.DedupeKey),
+		asynq.Queue(p.opts.Queue),
+		asynq.MaxRetry(p.opts.MaxRetry),
+		asynq.Deadline(time.Now().Add(p.opts.Deadline)),
+		asynq.Retention(p.opts.Retention),
+	)
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return errtrace.Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+)
+
+// Default settings of the relay.
+const (
+	DefaultPollInterval    = time.Second
+	DefaultBatchSize       = 100
+	DefaultBackoff         = time.Second
+	DefaultMaxBackoff      = time.Hour
+	DefaultCleanupAfter    = 7 * 24 * time.Hour
+	DefaultCleanupInterval = time.Hour
+)
+
+// Options defines the relay settings.
+type Options struct {
+	// PollInterval is the delay between the checks of the pending messages. Default: 1s.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of messages published per query. Default: 100.
+	BatchSize int
+	// Backoff is the delay before the first retry of a failed message, it's doubled
+	// on each next retry up to MaxBackoff. Defaults: 1s and 1h.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// CleanupAfter is the retention period of the published messages. Default: 7 days.
+	CleanupAfter time.Duration
+	// CleanupInterval is the delay between the deletions of the old published messages. Default: 1h.
+	CleanupInterval time.Duration
+	// OnError is called on publishing and database errors, e.g. to log them.
+	// The relay keeps running, the failed messages are retried.
+	OnError func(err error)
+}
+
+// Relay publishes the pending outbox messages in the order they were enqueued.
+type Relay struct {
+	q    repository.Querier
+	pub  Publisher
+	opts Options
+}
+
+// NewRelay creates a new relay. The repository must be bound to the primary database,
+// the messages written in a transaction must be visible to it right after the commit.
+func NewRelay(q repository.Querier, pub Publisher, opts Options) (*Relay, error) {
+	if q == nil {
+		return nil, errtrace.Wrap(ErrMissedDBConnection)
+	}
+	if pub == nil {
+		return nil, errtrace.Wrap(ErrMissedPublisher)
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.CleanupAfter <= 0 {
+		opts.CleanupAfter = DefaultCleanupAfter
+	}
+	if opts.CleanupInterval <= 0 {
+		opts.CleanupInterval = DefaultCleanupInterval
+	}
+	return &Relay{q: q, pub: pub, opts: opts}, nil
+}
+
+// Run publishes the pending messages until the context is canceled, e.g. run it in the main errgroup.
+// Several instances may run relays at the same time, the duplicates are dropped by the publisher.
+func (r *Relay) Run(ctx context.Context) error {
+	poll := time.NewTicker(r.opts.PollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(r.opts.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		if _, err := r.Publish(ctx); err != nil {
+			r.onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+		case <-cleanup.C:
+			if _, err := r.Cleanup(ctx); err != nil {
+				r.onError(err)
+			}
+		}
+	}
+}
+
+// Publish publishes the messages which are due now and returns the number of published ones.
+// A failed message is postponed with exponential backoff, its error is reported with OnError.
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		list, err := r.q.ListPendingOutboxMessages(ctx, repository.ListPendingOutboxMessagesParams{
+			Now:   now(),
+			Limit: int64(r.opts.BatchSize),
+		})
+		if err != nil {
+			return published, errtrace.Wrap(errors.Join(ErrFailedToFetchMessages, err))
+		}
+
+		for _, m := range list {
+			if ctx.Err() != nil {
+				return published, nil
+			}
+			ok, err := r.publish(ctx, m)
+			if err != nil {
+				return published, errtrace.Wrap(err)
+			}
+			if ok {
+				published++
+			}
+		}
+
+		// The failed messages are postponed, so the next batch has new ones
+		if len(list) < r.opts.BatchSize {
+			return published, nil
+		}
+	}
+}
+
+// publish publishes the message and records the result.
+// It returns an error only if the result can't be saved.
+func (r *Relay) publish(ctx context.Context, m repository.Outbox) (bool, error) {
+	if err := r.pub.Publish(ctx, m); err != nil {
+		r.onError(errors.Join(ErrFailedToPublishMessage, err))
+
+		err := r.q.MarkOutboxMessageFailed(ctx, repository.MarkOutboxMessageFailedParams{
+			ID:            m.ID,
+			LastError:     sql.NullString{String: err.Error(), Valid: true},
+			NextAttemptAt: now().Add(r.backoff(m.Attempts)),
+		})
+		if err != nil {
+			return false, errtrace.Wrap(errors.Join(ErrFailedToUpdateMessage, err))
+		}
+		return false, nil
+	}
+
+	// The message is published even if the request is canceled now, record it
+	err := r.q.MarkOutboxMessagePublished(context.WithoutCancel(ctx), repository.MarkOutboxMessagePublishedParams{
+		ID:          m.ID,
+		PublishedAt: sql.NullTime{Time: now(), Valid: true},
+	})
+	if err != nil {
+		return false, errtrace.Wrap(errors.Join(ErrFailedToUpdateMessage, err))
+	}
+	return true, nil
+}
+
+// Cleanup deletes the messages published before the retention period and returns their number.
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	n, err := r.q.DeletePublishedOutboxMessages(ctx, sql.NullTime{Time: now().Add(-r.opts.CleanupAfter), Valid: true})
+	if err != nil {
+		return 0, errtrace.Wrap(errors.Join(ErrFailedToCleanupMessages, err))
+	}
+	return n, nil
+}
+
+// backoff returns the delay before the next attempt of a message failed the given number of times.
+func (r *Relay) backoff(attempts int64) time.Duration {
+	d := r.opts.Backoff
+	for i := int64(0); i < attempts && d < r.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.opts.MaxBackoff)
+}
+
+// onError reports the error if the callback is set.
+func (r *Relay) onError(err error) {
+	if r.opts.OnError != nil {
+		r.opts.OnError(err)
+	}
+}
@@ -0,0 +1,15 @@
+package db
+
+import (
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	"github.com/dmitrymomot/go-app-template/db/repository/postgres"
+)
+
+// NewQuerier returns the repository generated for the dialect, e.g. the one returned by Open.
+// Both repositories implement the shared repository.Querier, so call sites don't depend on the dialect.
+func NewQuerier(dialect Dialect, db repository.DBTX) repository.Querier {
+	if dialect == DialectPostgres {
+		return postgres.NewRepository(db)
+	}
+	return repository.New(db)
+}
@@ -0,0 +1,329 @@
+// Package contract is the shared test suite of the repository.Querier implementations.
+// Both the SQLite and the Postgres repositories must pass it, so call sites behave
+// the same whatever database is used. Run it from a test of the package which opens the database:
+//
+//	func TestPostgresRepository(t *testing.T) {
+//		contract.Run(t, func(t *testing.T) repository.Querier {
+//			conn := openMigratedDB(t) // an empty database with the migrations applied
+//			return db.NewQuerier(db.DialectPostgres, conn)
+//		})
+//	}
+package contract
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/go-app-template/db/dberr"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+)
+
+// NewQuerierFunc returns the repository under test bound to an empty database with the migrations applied.
+// It's called for each test case, the database must be cleaned up with t.Cleanup.
+type NewQuerierFunc func(t *testing.T) repository.Querier
+
+// Run runs the contract test suite against the repository returned by newQuerier.
+func Run(t *testing.T, newQuerier NewQuerierFunc) {
+	t.Helper()
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, q repository.Querier)
+	}{
+		{"CreateAuthor", testCreateAuthor},
+		{"GetAuthor", testGetAuthor},
+		{"GetAuthorNotFound", testGetAuthorNotFound},
+		{"ListAuthors", testListAuthors},
+		{"UpdateAuthor", testUpdateAuthor},
+		{"DeleteAuthor", testDeleteAuthor},
+		{"CreateOutboxMessage", testCreateOutboxMessage},
+		{"ListPendingOutboxMessages", testListPendingOutboxMessages},
+		{"MarkOutboxMessageFailed", testMarkOutboxMessageFailed},
+		{"DeletePublishedOutboxMessages", testDeletePublishedOutboxMessages},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, context.Background(), newQuerier(t))
+		})
+	}
+}
+
+func testCreateAuthor(t *testing.T, ctx context.Context, q repository.Querier) {
+	a := createAuthor(t, ctx, q, "Brian Kernighan", "Co-author of The C Programming Language")
+	if a.ID <= 0 {
+		t.Errorf("CreateAuthor() ID = %d, want positive", a.ID)
+	}
+	if a.Name != "Brian Kernighan" {
+		t.Errorf("CreateAuthor() Name = %q, want %q", a.Name, "Brian Kernighan")
+	}
+	if !a.Bio.Valid || a.Bio.String != "Co-author of The C Programming Language" {
+		t.Errorf("CreateAuthor() Bio = %+v, want the given bio", a.Bio)
+	}
+
+	// NULL must round-trip as an invalid sql.NullString
+	b, err := q.CreateAuthor(ctx, repository.CreateAuthorParams{Name: "Anonymous"})
+	if err != nil {
+		t.Fatalf("CreateAuthor() error = %v", err)
+	}
+	if b.Bio.Valid {
+		t.Errorf("CreateAuthor() Bio = %+v, want NULL", b.Bio)
+	}
+	if b.ID == a.ID {
+		t.Errorf("CreateAuthor() ID = %d, want unique ID", b.ID)
+	}
+}
+
+func testGetAuthor(t *testing.T, ctx context.Context, q repository.Querier) {
+	want := createAuthor(t, ctx, q, "Rob Pike", "Co-creator of Go")
+
+	got, err := q.GetAuthor(ctx, want.ID)
+	if err != nil {
+		t.Fatalf("GetAuthor() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("GetAuthor() = %+v, want %+v", got, want)
+	}
+}
+
+func testGetAuthorNotFound(t *testing.T, ctx context.Context, q repository.Querier) {
+	_, err := q.GetAuthor(ctx, 404)
+	if !dberr.Is(err, dberr.ErrNotFound) {
+		t.Errorf("GetAuthor() error = %v, want %v", err, dberr.ErrNotFound)
+	}
+}
+
+func testListAuthors(t *testing.T, ctx context.Context, q repository.Querier) {
+	list, err := q.ListAuthors(ctx)
+	if err != nil {
+		t.Fatalf("ListAuthors() error = %v", err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("ListAuthors() = %d authors, want empty list", len(list))
+	}
+
+	createAuthor(t, ctx, q, "Ken Thompson", "")
+	createAuthor(t, ctx, q, "Alan Donovan", "")
+	createAuthor(t, ctx, q, "Robert Griesemer", "")
+
+	list, err = q.ListAuthors(ctx)
+	if err != nil {
+		t.Fatalf("ListAuthors() error = %v", err)
+	}
+	want := []string{"Alan Donovan", "Ken Thompson", "Robert Griesemer"}
+	if len(list) != len(want) {
+		t.Fatalf("ListAuthors() = %d authors, want %d", len(list), len(want))
+	}
+	for i, a := range list {
+		if a.Name != want[i] {
+			t.Errorf("ListAuthors()[%d].Name = %q, want %q (ordered by name)", i, a.Name, want[i])
+		}
+	}
+}
+
+func testUpdateAuthor(t *testing.T, ctx context.Context, q repository.Querier) {
+	a := createAuthor(t, ctx, q, "Ian Lance Taylor", "")
+	other := createAuthor(t, ctx, q, "Russ Cox", "")
+
+	err := q.UpdateAuthor(ctx, repository.UpdateAuthorParams{
+		ID:   a.ID,
+		Name: "Ian Taylor",
+		Bio:  sql.NullString{String: "Generics", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("UpdateAuthor() error = %v", err)
+	}
+
+	got, err := q.GetAuthor(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("GetAuthor() error = %v", err)
+	}
+	want := repository.Author{ID: a.ID, Name: "Ian Taylor", Bio: sql.NullString{String: "Generics", Valid: true}}
+	if got != want {
+		t.Errorf("GetAuthor() after update = %+v, want %+v", got, want)
+	}
+
+	// Other rows must stay untouched
+	if got, err := q.GetAuthor(ctx, other.ID); err != nil || got != other {
+		t.Errorf("GetAuthor() of another author = %+v, %v, want %+v", got, err, other)
+	}
+}
+
+func testDeleteAuthor(t *testing.T, ctx context.Context, q repository.Querier) {
+	a := createAuthor(t, ctx, q, "Dennis Ritchie", "")
+
+	if err := q.DeleteAuthor(ctx, a.ID); err != nil {
+		t.Fatalf("DeleteAuthor() error = %v", err)
+	}
+	if _, err := q.GetAuthor(ctx, a.ID); !dberr.Is(err, dberr.ErrNotFound) {
+		t.Errorf("GetAuthor() after delete error = %v, want %v", err, dberr.ErrNotFound)
+	}
+
+	// Deleting a missing row is not an error for an :exec query
+	if err := q.DeleteAuthor(ctx, a.ID); err != nil {
+		t.Errorf("DeleteAuthor() of a deleted author error = %v", err)
+	}
+}
+
+func testCreateOutboxMessage(t *testing.T, ctx context.Context, q repository.Querier) {
+	now := outboxNow()
+	m := createOutboxMessage(t, ctx, q, "send_email", "key-1", now)
+	if m.ID <= 0 {
+		t.Errorf("CreateOutboxMessage() ID = %d, want positive", m.ID)
+	}
+	if m.TaskName != "send_email" || m.Payload != `{"to":"gopher@example.com"}` || m.DedupeKey != "key-1" {
+		t.Errorf("CreateOutboxMessage() = %+v, want the given task", m)
+	}
+	if m.Attempts != 0 || m.LastError.Valid || m.PublishedAt.Valid {
+		t.Errorf("CreateOutboxMessage() = %+v, want a pending message", m)
+	}
+	if !m.CreatedAt.Equal(now) || !m.NextAttemptAt.Equal(now) {
+		t.Errorf("CreateOutboxMessage() times = %v, %v, want %v", m.CreatedAt, m.NextAttemptAt, now)
+	}
+
+	// A duplicate dedupe key is skipped and returns no rows
+	_, err := q.CreateOutboxMessage(ctx, repository.CreateOutboxMessageParams{
+		TaskName:      "send_email",
+		Payload:       "{}",
+		DedupeKey:     "key-1",
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	})
+	if !dberr.Is(err, dberr.ErrNotFound) {
+		t.Errorf("CreateOutboxMessage() of a duplicate error = %v, want %v", err, dberr.ErrNotFound)
+	}
+}
+
+func testListPendingOutboxMessages(t *testing.T, ctx context.Context, q repository.Querier) {
+	now := outboxNow()
+	first := createOutboxMessage(t, ctx, q, "task", "key-1", now.Add(-time.Minute))
+	second := createOutboxMessage(t, ctx, q, "task", "key-2", now)
+	createOutboxMessage(t, ctx, q, "task", "key-3", now.Add(time.Minute)) // not due yet
+	published := createOutboxMessage(t, ctx, q, "task", "key-4", now)
+
+	err := q.MarkOutboxMessagePublished(ctx, repository.MarkOutboxMessagePublishedParams{
+		ID:          published.ID,
+		PublishedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("MarkOutboxMessagePublished() error = %v", err)
+	}
+
+	list, err := q.ListPendingOutboxMessages(ctx, repository.ListPendingOutboxMessagesParams{Now: now, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListPendingOutboxMessages() error = %v", err)
+	}
+	if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+		t.Fatalf("ListPendingOutboxMessages() = %+v, want the due messages ordered by ID", list)
+	}
+
+	list, err = q.ListPendingOutboxMessages(ctx, repository.ListPendingOutboxMessagesParams{Now: now, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListPendingOutboxMessages() error = %v", err)
+	}
+	if len(list) != 1 || list[0].ID != first.ID {
+		t.Errorf("ListPendingOutboxMessages() with limit = %+v, want the first message", list)
+	}
+}
+
+func testMarkOutboxMessageFailed(t *testing.T, ctx context.Context, q repository.Querier) {
+	now := outboxNow()
+	m := createOutboxMessage(t, ctx, q, "task", "key-1", now)
+
+	err := q.MarkOutboxMessageFailed(ctx, repository.MarkOutboxMessageFailedParams{
+		ID:            m.ID,
+		LastError:     sql.NullString{String: "broker is down", Valid: true},
+		NextAttemptAt: now.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("MarkOutboxMessageFailed() error = %v", err)
+	}
+
+	// The message is postponed until the next attempt
+	list, err := q.ListPendingOutboxMessages(ctx, repository.ListPendingOutboxMessagesParams{Now: now, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListPendingOutboxMessages() error = %v", err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("ListPendingOutboxMessages() = %+v, want the failed message postponed", list)
+	}
+
+	list, err = q.ListPendingOutboxMessages(ctx, repository.ListPendingOutboxMessagesParams{Now: now.Add(time.Minute), Limit: 10})
+	if err != nil {
+		t.Fatalf("ListPendingOutboxMessages() error = %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("ListPendingOutboxMessages() = %d messages, want 1", len(list))
+	}
+	if got := list[0]; got.Attempts != 1 || got.LastError.String != "broker is down" || !got.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("ListPendingOutboxMessages()[0] = %+v, want 1 failed attempt", got)
+	}
+}
+
+func testDeletePublishedOutboxMessages(t *testing.T, ctx context.Context, q repository.Querier) {
+	now := outboxNow()
+	old := createOutboxMessage(t, ctx, q, "task", "key-1", now)
+	recent := createOutboxMessage(t, ctx, q, "task", "key-2", now)
+	createOutboxMessage(t, ctx, q, "task", "key-3", now) // pending
+
+	for id, at := range map[int64]time.Time{old.ID: now.Add(-time.Hour), recent.ID: now} {
+		err := q.MarkOutboxMessagePublished(ctx, repository.MarkOutboxMessagePublishedParams{
+			ID:          id,
+			PublishedAt: sql.NullTime{Time: at, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("MarkOutboxMessagePublished() error = %v", err)
+		}
+	}
+
+	n, err := q.DeletePublishedOutboxMessages(ctx, sql.NullTime{Time: now.Add(-time.Minute), Valid: true})
+	if err != nil {
+		t.Fatalf("DeletePublishedOutboxMessages() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("DeletePublishedOutboxMessages() = %d, want 1", n)
+	}
+
+	// The deleted dedupe key can be reused
+	createOutboxMessage(t, ctx, q, "task", "key-1", now)
+}
+
+// createAuthor creates an author, an empty bio is stored as NULL.
+func createAuthor(t *testing.T, ctx context.Context, q repository.Querier, name, bio string) repository.Author {
+	t.Helper()
+
+	a, err := q.CreateAuthor(ctx, repository.CreateAuthorParams{
+		Name: name,
+		Bio:  sql.NullString{String: bio, Valid: bio != ""},
+	})
+	if err != nil {
+		t.Fatalf("CreateAuthor(%q) error = %v", name, err)
+	}
+	return a
+}
+
+// createOutboxMessage creates a pending outbox message due at the given time.
+func createOutboxMessage(t *testing.T, ctx context.Context, q repository.Querier, task, key string, at time.Time) repository.Outbox {
+	t.Helper()
+
+	m, err := q.CreateOutboxMessage(ctx, repository.CreateOutboxMessageParams{
+		TaskName:      task,
+		Payload:       `{"to":"gopher@example.com"}`,
+		DedupeKey:     key,
+		CreatedAt:     at,
+		NextAttemptAt: at,
+	})
+	if err != nil {
+		t.Fatalf("CreateOutboxMessage(%q) error = %v", key, err)
+	}
+	return m
+}
+
+// outboxNow returns the current time in UTC truncated to seconds,
+// SQLite compares the stored times as text.
+func outboxNow() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
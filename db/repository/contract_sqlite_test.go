@@ -0,0 +1,17 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	"github.com/dmitrymomot/go-app-template/db/repository/contract"
+	"github.com/dmitrymomot/go-app-template/db/testdb"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	t.Parallel()
+
+	contract.Run(t, func(t *testing.T) repository.Querier {
+		return testdb.New(t, testdb.Options{})
+	})
+}
@@ -6,6 +6,7 @@ package repository
 
 import (
 	"database/sql"
+	"time"
 )
 
 type Author struct {
@@ -13,3 +14,15 @@ type Author struct {
 	Name string
 	Bio  sql.NullString
 }
+
+type Outbox struct {
+	ID            int64
+	TaskName      string
+	Payload       string
+	DedupeKey     string
+	Attempts      int64
+	LastError     sql.NullString
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	PublishedAt   sql.NullTime
+}
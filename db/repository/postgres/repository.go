@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+)
+
+// Repository adapts the Postgres queries to the shared repository.Querier,
+// so call sites don't depend on the database dialect.
+// The models of both packages have the same fields, so they are converted directly;
+// the conversion fails to compile if the queries of the dialects get out of sync.
+type Repository struct {
+	q *Queries
+}
+
+var _ repository.Querier = (*Repository)(nil)
+
+// NewRepository creates a new Postgres repository.
+func NewRepository(db DBTX) *Repository {
+	return &Repository{q: New(db)}
+}
+
+// CreateAuthor implements repository.Querier.
+func (r *Repository) CreateAuthor(ctx context.Context, arg repository.CreateAuthorParams) (repository.Author, error) {
+	a, err := r.q.CreateAuthor(ctx, CreateAuthorParams(arg))
+	return repository.Author(a), errtrace.Wrap(err)
+}
+
+// CreateOutboxMessage implements repository.Querier.
+func (r *Repository) CreateOutboxMessage(ctx context.Context, arg repository.CreateOutboxMessageParams) (repository.Outbox, error) {
+	m, err := r.q.CreateOutboxMessage(ctx, CreateOutboxMessageParams(arg))
+	return repository.Outbox(m), errtrace.Wrap(err)
+}
+
+// DeleteAuthor implements repository.Querier.
+func (r *Repository) DeleteAuthor(ctx context.Context, id int64) error {
+	return errtrace.Wrap(r.q.DeleteAuthor(ctx, id))
+}
+
+// DeletePublishedOutboxMessages implements repository.Querier.
+func (r *Repository) DeletePublishedOutboxMessages(ctx context.Context, before sql.NullTime) (int64, error) {
+	return errtrace.Wrap2(r.q.DeletePublishedOutboxMessages(ctx, before))
+}
+
+// GetAuthor implements repository.Querier.
+func (r *Repository) GetAuthor(ctx context.Context, id int64) (repository.Author, error) {
+	a, err := r.q.GetAuthor(ctx, id)
+	return repository.Author(a), errtrace.Wrap(err)
+}
+
+// ListAuthors implements repository.Querier.
+func (r *Repository) ListAuthors(ctx context.Context) ([]repository.Author, error) {
+	list, err := r.q.ListAuthors(ctx)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	result := make([]repository.Author, 0, len(list))
+	for _, a := range list {
+		result = append(result, repository.Author(a))
+	}
+	return result, nil
+}
+
+// ListPendingOutboxMessages implements repository.Querier.
+func (r *Repository) ListPendingOutboxMessages(ctx context.Context, arg repository.ListPendingOutboxMessagesParams) ([]repository.Outbox, error) {
+	list, err := r.q.ListPendingOutboxMessages(ctx, ListPendingOutboxMessagesParams(arg))
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	result := make([]repository.Outbox, 0, len(list))
+	for _, m := range list {
+		result = append(result, repository.Outbox(m))
+	}
+	return result, nil
+}
+
+// MarkOutboxMessageFailed implements repository.Querier.
+func (r *Repository) MarkOutboxMessageFailed(ctx context.Context, arg repository.MarkOutboxMessageFailedParams) error {
+	return errtrace.Wrap(r.q.MarkOutboxMessageFailed(ctx, MarkOutboxMessageFailedParams(arg)))
+}
+
+// MarkOutboxMessagePublished implements repository.Querier.
+func (r *Repository) MarkOutboxMessagePublished(ctx context.Context, arg repository.MarkOutboxMessagePublishedParams) error {
+	return errtrace.Wrap(r.q.MarkOutboxMessagePublished(ctx, MarkOutboxMessagePublishedParams(arg)))
+}
+
+// UpdateAuthor implements repository.Querier.
+func (r *Repository) UpdateAuthor(ctx context.Context, arg repository.UpdateAuthorParams) error {
+	return errtrace.Wrap(r.q.UpdateAuthor(ctx, UpdateAuthorParams(arg)))
+}
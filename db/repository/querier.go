@@ -6,13 +6,20 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
 	CreateAuthor(ctx context.Context, arg CreateAuthorParams) (Author, error)
+	// Returns no rows if a message with the same dedupe key exists.
+	CreateOutboxMessage(ctx context.Context, arg CreateOutboxMessageParams) (Outbox, error)
 	DeleteAuthor(ctx context.Context, id int64) error
+	DeletePublishedOutboxMessages(ctx context.Context, before sql.NullTime) (int64, error)
 	GetAuthor(ctx context.Context, id int64) (Author, error)
 	ListAuthors(ctx context.Context) ([]Author, error)
+	ListPendingOutboxMessages(ctx context.Context, arg ListPendingOutboxMessagesParams) ([]Outbox, error)
+	MarkOutboxMessageFailed(ctx context.Context, arg MarkOutboxMessageFailedParams) error
+	MarkOutboxMessagePublished(ctx context.Context, arg MarkOutboxMessagePublishedParams) error
 	UpdateAuthor(ctx context.Context, arg UpdateAuthorParams) error
 }
 
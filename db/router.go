@@ -0,0 +1,252 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"braces.dev/errtrace"
+)
+
+// Default replica health check settings of Router.
+const (
+	defaultReplicaCheckInterval = 5 * time.Second
+	defaultReplicaCheckTimeout  = time.Second
+)
+
+// RouterOptions defines the replica health check settings of Router.
+type RouterOptions struct {
+	// HealthCheckInterval is how often replicas are pinged. Default: 5s.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout limits each ping. Default: 1s.
+	HealthCheckTimeout time.Duration
+	// OnReplicaStateChange is called when a replica becomes unhealthy or recovers, e.g. to log it.
+	// The index is the position of the replica in the list passed to NewRouter.
+	OnReplicaStateChange func(index int, healthy bool, err error)
+}
+
+// Router is a repository.DBTX which sends reads to a pool of read replicas
+// and everything else to the primary database, so the sqlc repository can use replicas unchanged:
+//
+//	q := db.NewQuerier(dialect, router)
+//
+// Read queries (SELECT and WITH without data-modifying statements) are distributed round-robin
+// among healthy replicas, falling back to the primary if none is healthy.
+// Writes, queries with RETURNING, locking reads, prepared statements and transactions use the primary,
+// so pass Primary to RunInTx.
+//
+// Replication is asynchronous, use WithPrimary or WithReadYourWrites on the request context
+// when the reads must see the preceding writes.
+type Router struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+	opts     RouterOptions
+
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// replica is a read replica with its health state.
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// NewRouter creates a new router over the primary database and its read replicas,
+// and starts the periodic replica health checks. Replicas are considered healthy initially.
+// Close stops the health checks, the connections are owned by the caller.
+func NewRouter(primary *sql.DB, replicas []*sql.DB, opts RouterOptions) *Router {
+	if opts.HealthCheckInterval <= 0 {
+		opts.HealthCheckInterval = defaultReplicaCheckInterval
+	}
+	if opts.HealthCheckTimeout <= 0 {
+		opts.HealthCheckTimeout = defaultReplicaCheckTimeout
+	}
+
+	r := &Router{
+		primary: primary,
+		opts:    opts,
+		stop:    make(chan struct{}),
+	}
+	for _, db := range replicas {
+		rep := &replica{db: db}
+		rep.healthy.Store(true)
+		r.replicas = append(r.replicas, rep)
+	}
+
+	if len(r.replicas) > 0 {
+		r.wg.Add(1)
+		go r.checkReplicas()
+	}
+
+	return r
+}
+
+// Primary returns the primary database, e.g. to run transactions with RunInTx.
+func (r *Router) Primary() *sql.DB {
+	return r.primary
+}
+
+// ExecContext executes the query on the primary database.
+func (r *Router) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := r.primary.ExecContext(ctx, query, args...)
+	markWritten(ctx)
+	return res, errtrace.Wrap(err)
+}
+
+// PrepareContext prepares the statement on the primary database,
+// as the statement may be used for writes.
+func (r *Router) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return errtrace.Wrap2(r.primary.PrepareContext(ctx, query))
+}
+
+// QueryContext executes the query on a replica if it's a read query, or on the primary otherwise.
+func (r *Router) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	read := isReadQuery(query)
+	rows, err := r.pick(ctx, read).QueryContext(ctx, query, args...)
+	if !read {
+		markWritten(ctx)
+	}
+	return rows, errtrace.Wrap(err)
+}
+
+// QueryRowContext executes the query on a replica if it's a read query, or on the primary otherwise.
+func (r *Router) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	read := isReadQuery(query)
+	row := r.pick(ctx, read).QueryRowContext(ctx, query, args...)
+	if !read {
+		markWritten(ctx)
+	}
+	return row
+}
+
+// BeginTx starts a transaction on the primary database.
+// Unless the transaction is read-only, it's taken as a write for WithReadYourWrites.
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := r.primary.BeginTx(ctx, opts)
+	if opts == nil || !opts.ReadOnly {
+		markWritten(ctx)
+	}
+	return tx, errtrace.Wrap(err)
+}
+
+// Close stops the replica health checks. It doesn't close the connections.
+func (r *Router) Close() {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		r.wg.Wait()
+	})
+}
+
+// pick returns the database to run the query on.
+func (r *Router) pick(ctx context.Context, read bool) *sql.DB {
+	if !read || len(r.replicas) == 0 || usePrimary(ctx) {
+		return r.primary
+	}
+
+	// Round-robin over healthy replicas starting from the next one
+	start := r.next.Add(1)
+	for i := 0; i < len(r.replicas); i++ {
+		rep := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if rep.healthy.Load() {
+			return rep.db
+		}
+	}
+
+	return r.primary
+}
+
+// checkReplicas pings the replicas periodically until the router is closed.
+func (r *Router) checkReplicas() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.opts.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+
+		for i, rep := range r.replicas {
+			ctx, cancel := context.WithTimeout(context.Background(), r.opts.HealthCheckTimeout)
+			err := rep.db.PingContext(ctx)
+			cancel()
+
+			healthy := err == nil
+			if rep.healthy.Swap(healthy) != healthy && r.opts.OnReplicaStateChange != nil {
+				if err != nil {
+					err = errors.Join(ErrFailedToPingDB, err)
+				}
+				r.opts.OnReplicaStateChange(i, healthy, err)
+			}
+		}
+	}
+}
+
+// routingKey is the context key of the routing override.
+type routingKey struct{}
+
+// routing is the routing override carried by the context.
+type routing struct {
+	always  bool        // WithPrimary: all queries go to the primary
+	written atomic.Bool // WithReadYourWrites: set after the first write
+}
+
+// WithPrimary returns the context which sends all queries to the primary database.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routingKey{}, &routing{always: true})
+}
+
+// WithReadYourWrites returns the context which sends reads to the primary database
+// after the first write made with this context, e.g. for the rest of the HTTP request.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(routingKey{}).(*routing); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, routingKey{}, &routing{})
+}
+
+// usePrimary reports whether the context requires the primary database.
+func usePrimary(ctx context.Context) bool {
+	rt, ok := ctx.Value(routingKey{}).(*routing)
+	return ok && (rt.always || rt.written.Load())
+}
+
+// markWritten records the write for WithReadYourWrites.
+func markWritten(ctx context.Context) {
+	if rt, ok := ctx.Value(routingKey{}).(*routing); ok {
+		rt.written.Store(true)
+	}
+}
+
+var (
+	// Statements which modify data or lock rows
+	writeQueryRe = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|RETURNING|FOR\s+(NO\s+KEY\s+)?UPDATE|FOR\s+(KEY\s+)?SHARE)\b`)
+	// Leading comments, e.g. the sqlc "-- name: GetAuthor :one" comment
+	leadingCommentsRe = regexp.MustCompile(`(?s)^(\s*(--[^\n]*\n|/\*.*?\*/))*\s*`)
+)
+
+// isReadQuery reports whether the query only reads data and can be sent to a replica.
+func isReadQuery(query string) bool {
+	query = leadingCommentsRe.ReplaceAllString(query, "")
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch keyword := strings.ToUpper(fields[0]); {
+	case keyword == "SELECT", keyword == "WITH", strings.HasPrefix(keyword, "("):
+		return !writeQueryRe.MatchString(query)
+	}
+	return false
+}
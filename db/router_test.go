@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	_ "github.com/tursodatabase/go-libsql" // init libSQL driver
+)
+
+func TestIsReadQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT * FROM authors", true},
+		{"select id from authors where id = ?", true},
+		{"-- name: GetAuthor :one\nSELECT * FROM authors WHERE id = ?", true},
+		{"/* comment */ SELECT 1", true},
+		{"WITH a AS (SELECT 1) SELECT * FROM a", true},
+		{"(SELECT 1) UNION (SELECT 2)", true},
+		{"SELECT updated_at FROM authors", true},
+		{"INSERT INTO authors (name) VALUES (?)", false},
+		{"UPDATE authors SET name = ?", false},
+		{"DELETE FROM authors", false},
+		{"-- name: CreateAuthor :one\nINSERT INTO authors (name) VALUES (?) RETURNING *", false},
+		{"WITH d AS (DELETE FROM authors RETURNING id) SELECT * FROM d", false},
+		{"SELECT * FROM authors FOR UPDATE", false},
+		{"SELECT * FROM authors FOR NO KEY UPDATE", false},
+		{"SELECT * FROM authors FOR SHARE", false},
+		{"CREATE TABLE t (id INTEGER)", false},
+		{"PRAGMA table_info(authors)", false},
+		{"", false},
+		{"-- only a comment\n", false},
+	}
+
+	for _, tt := range tests {
+		if got := isReadQuery(tt.query); got != tt.want {
+			t.Errorf("isReadQuery(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestRouterReadYourWritesAfterTx(t *testing.T) {
+	t.Parallel()
+
+	primary, replica := openTestDB(t), openTestDB(t)
+	router := NewRouter(primary, []*sql.DB{replica}, RouterOptions{})
+	t.Cleanup(router.Close)
+
+	// The replica lags behind: the author is written only to the primary
+	ctx := WithReadYourWrites(context.Background())
+	if router.pick(ctx, true) != replica {
+		t.Fatal("pick() before the write = primary, want the replica")
+	}
+	err := RunInTx(ctx, router.Primary(), TxOptions{Dialect: DialectSQLite}, func(ctx context.Context, q repository.Querier) error {
+		_, err := q.CreateAuthor(ctx, repository.CreateAuthorParams{Name: "Rob Pike"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("RunInTx() error = %v", err)
+	}
+
+	authors, err := NewQuerier(DialectSQLite, router).ListAuthors(ctx)
+	if err != nil {
+		t.Fatalf("ListAuthors() error = %v", err)
+	}
+	if len(authors) != 1 {
+		t.Errorf("ListAuthors() after the transaction returned %d authors, want the read sent to the primary", len(authors))
+	}
+}
+
+func TestRouterBeginTx(t *testing.T) {
+	t.Parallel()
+
+	primary, replica := openTestDB(t), openTestDB(t)
+	router := NewRouter(primary, []*sql.DB{replica}, RouterOptions{})
+	t.Cleanup(router.Close)
+
+	ctx := WithReadYourWrites(context.Background())
+	tx, err := router.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	_ = tx.Rollback()
+	if router.pick(ctx, true) != primary {
+		t.Error("pick() after a transaction = replica, want the primary")
+	}
+}
+
+// openTestDB opens a new SQLite database with the authors table.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	conn, err := sql.Open(driverLibSQL, "file:"+t.TempDir()+"/test.db")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	if _, err := conn.Exec(`CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL, bio TEXT)`); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	return conn
+}
@@ -0,0 +1,17 @@
+package seed
+
+import "errors"
+
+// Predefined errors.
+var (
+	ErrProductionEnv        = errors.New("seeding is not allowed in the production environment")
+	ErrMissedDBConnection   = errors.New("missed db connection")
+	ErrFailedToLoadFixtures = errors.New("failed to load fixtures")
+	ErrInvalidFixture       = errors.New("invalid fixture")
+	ErrUnknownReference     = errors.New("unknown reference")
+	ErrDuplicateReference   = errors.New("duplicate reference")
+	ErrUnknownFakeValue     = errors.New("unknown fake value")
+	ErrFailedToSeedTable    = errors.New("failed to seed table")
+	ErrFailedToRunGoSeed    = errors.New("failed to run go seed")
+	ErrFailedToSeedDatabase = errors.New("failed to seed database")
+)
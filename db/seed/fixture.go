@@ -0,0 +1,157 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"braces.dev/errtrace"
+	"gopkg.in/yaml.v3"
+)
+
+// Record field names with a special meaning, they are not stored in the table.
+const (
+	refField = "_ref" // the name other records use to reference this record
+)
+
+// Fixture is the list of records of a single table.
+//
+// The fixture files are YAML or JSON lists of fixtures:
+//
+//	# db/seeds/local/01-authors.yaml
+//	- table: authors
+//	  key: [name]
+//	  records:
+//	    - _ref: rob
+//	      name: Rob Pike
+//	      bio: $fake:sentence
+//
+// String values starting with "$ref:" and "$fake:" are replaced, see Resolve.
+//
+// Fake values depend on the fake seed and on the order of the fake values in the fixtures,
+// so a record keyed by a fake value, e.g. name: $fake:name with key: [name], is inserted again
+// after the seed changes or a fake value is added before it. Key such records by a fixed column:
+//
+//	# fake authors
+//	- table: authors
+//	  key: [id]
+//	  records:
+//	    - id: 1001
+//	      name: $fake:name
+type Fixture struct {
+	Table string `json:"table" yaml:"table"`
+	// Key are the columns identifying a record, existing records are updated instead of inserted,
+	// so the seeding can be run many times. Default: id.
+	Key     []string         `json:"key" yaml:"key"`
+	Records []map[string]any `json:"records" yaml:"records"`
+	// File is the name of the file the fixture was loaded from.
+	File string `json:"-" yaml:"-"`
+}
+
+// Table and column names must be plain identifiers, as they can't be passed as query arguments.
+var identifierRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// LoadFixtures loads the fixtures of the environment from the directory dir/env of the file system.
+// Files with the .yaml, .yml and .json extensions are loaded in the lexical order of their names,
+// so the names can be prefixed with numbers to order the tables referencing each other.
+// A missing directory means there are no fixtures for the environment.
+func LoadFixtures(fsys fs.FS, dir, env string) ([]Fixture, error) {
+	dir = path.Join(dir, env)
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToLoadFixtures, err))
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		switch path.Ext(e.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var result []Fixture
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, errtrace.Wrap(errors.Join(ErrFailedToLoadFixtures, err))
+		}
+		list, err := ParseFixtures(name, data)
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		result = append(result, list...)
+	}
+
+	return result, nil
+}
+
+// ParseFixtures parses the content of the fixture file, the format is chosen by the file extension.
+func ParseFixtures(name string, data []byte) ([]Fixture, error) {
+	var list []Fixture
+	if path.Ext(name) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&list); err != nil {
+			return nil, errtrace.Wrap(errors.Join(ErrInvalidFixture, fmt.Errorf("%s: %w", name, err)))
+		}
+	} else if err := yaml.Unmarshal(data, &list); err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrInvalidFixture, fmt.Errorf("%s: %w", name, err)))
+	}
+
+	for i := range list {
+		list[i].File = name
+		if err := list[i].validate(); err != nil {
+			return nil, errtrace.Wrap(errors.Join(ErrInvalidFixture, fmt.Errorf("%s: %w", name, err)))
+		}
+	}
+
+	return list, nil
+}
+
+// validate checks the identifiers and sets the default key.
+func (f *Fixture) validate() error {
+	if !identifierRe.MatchString(f.Table) {
+		return fmt.Errorf("invalid table name %q", f.Table)
+	}
+	if len(f.Key) == 0 {
+		f.Key = []string{"id"}
+	}
+	for _, k := range f.Key {
+		if !identifierRe.MatchString(k) {
+			return fmt.Errorf("table %s: invalid key column %q", f.Table, k)
+		}
+	}
+
+	for i, r := range f.Records {
+		for col := range r {
+			if col != refField && !identifierRe.MatchString(col) {
+				return fmt.Errorf("table %s, record #%d: invalid column name %q", f.Table, i, col)
+			}
+		}
+		for _, k := range f.Key {
+			if _, ok := r[k]; !ok {
+				return fmt.Errorf("table %s, record #%d: missing key column %q", f.Table, i, k)
+			}
+		}
+		if ref, ok := r[refField]; ok {
+			if s, ok := ref.(string); !ok || strings.TrimSpace(s) == "" {
+				return fmt.Errorf("table %s, record #%d: %s must be a non-empty string", f.Table, i, refField)
+			}
+		}
+	}
+
+	return nil
+}
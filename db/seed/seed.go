@@ -0,0 +1,306 @@
+// Package seed fills the database with the development data declared in fixture files,
+// see Fixture for the format. Data which needs Go logic is seeded through the repository
+// by the functions registered with Register.
+//
+// Seeding is idempotent: records are matched by the fixture key columns and updated if they exist,
+// and fake values are generated with a fixed seed, so running it again doesn't duplicate the data.
+// It refuses to run in the production environment.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+)
+
+// productionEnv is the APP_ENV value of the production environment.
+const productionEnv = "production"
+
+// Options defines the seeding settings.
+type Options struct {
+	// Env is the environment to seed, it selects the fixtures directory and the Go seeds.
+	Env string
+	// Dialect is the SQL dialect of the database. Default: SQLite.
+	Dialect db.Dialect
+	// FakeSeed seeds the fake values generator. The same seed produces the same values.
+	FakeSeed int64
+}
+
+// Result is the number of seeded records of a table.
+type Result struct {
+	Table    string `json:"table"`
+	Inserted int    `json:"inserted"`
+	Updated  int    `json:"updated"`
+}
+
+// GoSeedFunc seeds the data through the repository. The refs contain the records seeded
+// from the fixtures. It runs in the seeding transaction and must be idempotent itself.
+type GoSeedFunc func(ctx context.Context, q repository.Querier, refs Refs) error
+
+// GoSeed is the seeding function for the listed environments, all environments if the list is empty.
+type GoSeed struct {
+	Name string
+	Envs []string
+	Run  GoSeedFunc
+}
+
+var (
+	goSeedsMu sync.RWMutex
+	goSeeds   []GoSeed
+)
+
+// Register adds the Go seed, it runs after the fixtures in the order of registration.
+// It is intended to be called from init functions. It panics if the name is empty or Run is nil.
+func Register(s GoSeed) {
+	goSeedsMu.Lock()
+	defer goSeedsMu.Unlock()
+
+	if s.Name == "" {
+		panic("seed: Register called with empty name")
+	}
+	if s.Run == nil {
+		panic("seed: Register called with nil Run for " + s.Name)
+	}
+	goSeeds = append(goSeeds, s)
+}
+
+// registered returns the Go seeds of the environment.
+func registered(env string) []GoSeed {
+	goSeedsMu.RLock()
+	defer goSeedsMu.RUnlock()
+
+	var result []GoSeed
+	for _, s := range goSeeds {
+		if len(s.Envs) == 0 || slices.Contains(s.Envs, env) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// Run seeds the fixtures and the registered Go seeds in a single transaction,
+// so the database is left unchanged if any record fails.
+func Run(ctx context.Context, conn *sql.DB, fixtures []Fixture, opts Options) ([]Result, error) {
+	if strings.EqualFold(opts.Env, productionEnv) {
+		return nil, errtrace.Wrap(ErrProductionEnv)
+	}
+	if conn == nil {
+		return nil, errtrace.Wrap(ErrMissedDBConnection)
+	}
+	if opts.Dialect == "" {
+		opts.Dialect = db.DialectSQLite
+	}
+
+	var result []Result
+	err := db.RunInTx(ctx, conn, db.TxOptions{Dialect: opts.Dialect}, func(ctx context.Context, q repository.Querier) error {
+		tx, _ := db.TxFromContext(ctx)
+		s := &seeder{tx: tx, dialect: opts.Dialect, refs: make(Refs), fake: NewFaker(opts.FakeSeed)}
+
+		// The transaction may be retried, so the result is collected from scratch
+		result = result[:0]
+		for _, f := range fixtures {
+			res, err := s.seed(ctx, f)
+			if err != nil {
+				return errtrace.Wrap(errors.Join(ErrFailedToSeedTable, fmt.Errorf("%s (%s): %w", f.Table, f.File, err)))
+			}
+			result = append(result, res)
+		}
+
+		for _, gs := range registered(opts.Env) {
+			if err := gs.Run(ctx, q, s.refs); err != nil {
+				return errtrace.Wrap(errors.Join(ErrFailedToRunGoSeed, fmt.Errorf("%s: %w", gs.Name, err)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToSeedDatabase, err))
+	}
+
+	return result, nil
+}
+
+// seeder upserts the fixture records within the transaction.
+type seeder struct {
+	tx      *sql.Tx
+	dialect db.Dialect
+	refs    Refs
+	fake    *Faker
+}
+
+// seed upserts the records of the fixture.
+func (s *seeder) seed(ctx context.Context, f Fixture) (Result, error) {
+	res := Result{Table: f.Table}
+
+	for i, r := range f.Records {
+		values := make(map[string]any, len(r))
+		ref, _ := r[refField].(string)
+
+		// The columns are resolved in a stable order, so the seeded faker generates
+		// the same values for them on every run and the records are matched by their keys.
+		cols := make([]string, 0, len(r))
+		for col := range r {
+			if col != refField {
+				cols = append(cols, col)
+			}
+		}
+		sort.Strings(cols)
+
+		for _, col := range cols {
+			resolved, err := Resolve(r[col], s.refs, s.fake)
+			if err != nil {
+				return res, errtrace.Wrap(fmt.Errorf("record #%d, column %s: %w", i, col, err))
+			}
+			values[col] = resolved
+		}
+
+		inserted, row, err := s.upsert(ctx, f.Table, f.Key, values)
+		if err != nil {
+			return res, errtrace.Wrap(fmt.Errorf("record #%d: %w", i, err))
+		}
+		if inserted {
+			res.Inserted++
+		} else {
+			res.Updated++
+		}
+
+		if ref != "" {
+			if _, dup := s.refs[ref]; dup {
+				return res, errtrace.Wrap(fmt.Errorf("record #%d: %w: %q", i, ErrDuplicateReference, ref))
+			}
+			s.refs[ref] = row
+		}
+	}
+
+	return res, nil
+}
+
+// upsert updates the record matching the key columns or inserts a new one,
+// and returns the stored record. Key values must not be NULL.
+func (s *seeder) upsert(ctx context.Context, table string, key []string, values map[string]any) (bool, map[string]any, error) {
+	cols := make([]string, 0, len(values))
+	for col := range values {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	var (
+		where     []string
+		whereArgs []any
+	)
+	for _, k := range key {
+		if values[k] == nil {
+			return false, nil, errtrace.Wrap(fmt.Errorf("%w: key column %s is NULL", ErrInvalidFixture, k))
+		}
+		where = append(where, fmt.Sprintf("%s = %s", quote(k), s.bindVar(len(whereArgs))))
+		whereArgs = append(whereArgs, values[k])
+	}
+	cond := strings.Join(where, " AND ")
+
+	var exists int
+	err := s.tx.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", quote(table), cond), whereArgs...,
+	).Scan(&exists)
+	if err != nil {
+		return false, nil, errtrace.Wrap(err)
+	}
+	if exists > 1 {
+		return false, nil, errtrace.Wrap(fmt.Errorf("%w: key (%s) matches %d records", ErrInvalidFixture, strings.Join(key, ", "), exists))
+	}
+
+	args := make([]any, 0, len(cols)+len(whereArgs))
+	if exists == 0 {
+		binds := make([]string, len(cols))
+		quoted := make([]string, len(cols))
+		for i, col := range cols {
+			quoted[i], binds[i] = quote(col), s.bindVar(i)
+			args = append(args, values[col])
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quote(table), strings.Join(quoted, ", "), strings.Join(binds, ", "))
+		if _, err := s.tx.ExecContext(ctx, query, args...); err != nil {
+			return false, nil, errtrace.Wrap(err)
+		}
+	} else if len(cols) > len(key) {
+		set := make([]string, 0, len(cols))
+		for _, col := range cols {
+			if slices.Contains(key, col) {
+				continue
+			}
+			set = append(set, fmt.Sprintf("%s = %s", quote(col), s.bindVar(len(args))))
+			args = append(args, values[col])
+		}
+		// The key arguments follow the SET arguments
+		where = where[:0]
+		for _, k := range key {
+			where = append(where, fmt.Sprintf("%s = %s", quote(k), s.bindVar(len(args))))
+			args = append(args, values[k])
+		}
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", quote(table), strings.Join(set, ", "), strings.Join(where, " AND "))
+		if _, err := s.tx.ExecContext(ctx, query, args...); err != nil {
+			return false, nil, errtrace.Wrap(err)
+		}
+	}
+
+	row, err := s.selectRow(ctx, table, cond, whereArgs)
+	if err != nil {
+		return false, nil, errtrace.Wrap(err)
+	}
+	return exists == 0, row, nil
+}
+
+// selectRow returns the columns of the record matching the condition.
+func (s *seeder) selectRow(ctx context.Context, table, cond string, args []any) (map[string]any, error) {
+	rows, err := s.tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s", quote(table), cond), args...)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	if !rows.Next() {
+		return nil, errtrace.Wrap(errors.Join(sql.ErrNoRows, rows.Err()))
+	}
+
+	values := make([]any, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	row := make(map[string]any, len(cols))
+	for i, col := range cols {
+		if b, ok := values[i].([]byte); ok {
+			values[i] = string(b)
+		}
+		row[col] = values[i]
+	}
+	return row, errtrace.Wrap(rows.Err())
+}
+
+// bindVar returns the placeholder of the i-th (zero-based) query argument.
+func (s *seeder) bindVar(i int) string {
+	if s.dialect == db.DialectPostgres {
+		return fmt.Sprintf("$%d", i+1)
+	}
+	return "?"
+}
+
+// quote quotes the identifier, it's validated by the fixture parser.
+func quote(name string) string {
+	return `"` + name + `"`
+}
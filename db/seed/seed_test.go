@@ -0,0 +1,308 @@
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dmitrymomot/go-app-template/db/testdb"
+)
+
+func TestParseFixtures(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    []Fixture
+		wantErr bool
+	}{
+		{
+			name: "yaml with default key",
+			file: "01-authors.yaml",
+			data: "- table: authors\n  records:\n    - id: 1\n      name: Rob Pike\n",
+			want: []Fixture{{
+				Table:   "authors",
+				Key:     []string{"id"},
+				Records: []map[string]any{{"id": 1, "name": "Rob Pike"}},
+				File:    "01-authors.yaml",
+			}},
+		},
+		{
+			name: "json numbers",
+			file: "01-authors.json",
+			data: `[{"table": "authors", "key": ["name"], "records": [{"name": "Rob Pike", "id": 1}]}]`,
+			want: []Fixture{{
+				Table:   "authors",
+				Key:     []string{"name"},
+				Records: []map[string]any{{"id": json.Number("1"), "name": "Rob Pike"}},
+				File:    "01-authors.json",
+			}},
+		},
+		{
+			name: "ref field is not a column",
+			file: "01-authors.yaml",
+			data: "- table: authors\n  key: [name]\n  records:\n    - _ref: rob\n      name: Rob Pike\n",
+			want: []Fixture{{
+				Table:   "authors",
+				Key:     []string{"name"},
+				Records: []map[string]any{{"_ref": "rob", "name": "Rob Pike"}},
+				File:    "01-authors.yaml",
+			}},
+		},
+		{name: "invalid table name", file: "a.yaml", data: "- table: authors; DROP TABLE authors\n", wantErr: true},
+		{name: "invalid key column", file: "a.yaml", data: "- table: authors\n  key: [\"na me\"]\n", wantErr: true},
+		{name: "invalid column name", file: "a.yaml", data: "- table: authors\n  records:\n    - id: 1\n      \"na-me\": x\n", wantErr: true},
+		{name: "missing key column", file: "a.yaml", data: "- table: authors\n  key: [name]\n  records:\n    - bio: x\n", wantErr: true},
+		{name: "empty ref", file: "a.yaml", data: "- table: authors\n  records:\n    - id: 1\n      _ref: \" \"\n", wantErr: true},
+		{name: "invalid yaml", file: "a.yaml", data: "table: authors\n", wantErr: true},
+		{name: "invalid json", file: "a.json", data: `{"table": "authors"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseFixtures(tt.file, []byte(tt.data))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFixture) {
+					t.Fatalf("ParseFixtures() error = %v, want %v", err, ErrInvalidFixture)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFixtures() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFixtures() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	refs := Refs{"rob": {"id": int64(1), "name": "Rob Pike"}}
+	tests := []struct {
+		name    string
+		value   any
+		want    any
+		wantErr error
+	}{
+		{name: "plain string", value: "Rob Pike", want: "Rob Pike"},
+		{name: "ref column", value: "$ref:rob.name", want: "Rob Pike"},
+		{name: "ref id by default", value: "$ref:rob", want: int64(1)},
+		{name: "escaped ref", value: "$$ref:rob.name", want: "$ref:rob.name"},
+		{name: "escaped fake", value: "$$fake:name", want: "$fake:name"},
+		{name: "escaped dollars", value: "$$$", want: "$$"},
+		{name: "single dollar", value: "$5", want: "$5"},
+		{name: "unknown ref", value: "$ref:ken.id", wantErr: ErrUnknownReference},
+		{name: "unknown ref column", value: "$ref:rob.bio", wantErr: ErrUnknownReference},
+		{name: "unknown fake kind", value: "$fake:planet", wantErr: ErrUnknownFakeValue},
+		{name: "json int", value: json.Number("42"), want: int64(42)},
+		{name: "json float", value: json.Number("4.2"), want: 4.2},
+		{name: "yaml int", value: 42, want: int64(42)},
+		{name: "null", value: nil, want: nil},
+		{name: "bool", value: true, want: true},
+		{name: "unsupported type", value: []any{1}, wantErr: ErrInvalidFixture},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Resolve(tt.value, refs, NewFaker(1))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Resolve(%v) error = %v, want %v", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%v) error = %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%v) = %#v, want %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFaker(t *testing.T) {
+	t.Parallel()
+
+	kinds := []string{"name", "first_name", "last_name", "username", "email", "phone", "url", "uuid", "bool", "word", "sentence", "paragraph", "int", "int:5:7"}
+	generate := func(seed int64) []any {
+		f := NewFaker(seed)
+		values := make([]any, 0, len(kinds))
+		for _, kind := range kinds {
+			v, err := f.Value(kind)
+			if err != nil {
+				t.Fatalf("Value(%q) error = %v", kind, err)
+			}
+			values = append(values, v)
+		}
+		return values
+	}
+
+	if a, b := generate(1), generate(1); !reflect.DeepEqual(a, b) {
+		t.Errorf("the same seed generated different values:\n%v\n%v", a, b)
+	}
+	if a, b := generate(1), generate(2); reflect.DeepEqual(a, b) {
+		t.Errorf("different seeds generated the same values: %v", a)
+	}
+
+	f := NewFaker(1)
+	for i := 0; i < 100; i++ {
+		v, err := f.Value("int:5:7")
+		if n, _ := v.(int64); err != nil || n < 5 || n > 7 {
+			t.Fatalf("Value(int:5:7) = %v, %v, want a value in [5, 7]", v, err)
+		}
+	}
+	for _, kind := range []string{"int:7:5", "int:5", "int:a:b"} {
+		if _, err := f.Value(kind); !errors.Is(err, ErrUnknownFakeValue) {
+			t.Errorf("Value(%q) error = %v, want %v", kind, err, ErrUnknownFakeValue)
+		}
+	}
+}
+
+func TestRunIdempotent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	fixtures, err := ParseFixtures("01-authors.yaml", []byte(`
+- table: authors
+  key: [name]
+  records:
+    - _ref: rob
+      name: Rob Pike
+      bio: $$ref:rob.name
+    - name: Ken Thompson
+      bio: $fake:sentence
+- table: authors
+  key: [id]
+  records:
+    - id: 100
+      name: $fake:name
+      bio: $ref:rob.name
+`))
+	if err != nil {
+		t.Fatalf("ParseFixtures() error = %v", err)
+	}
+	conn := testdb.Open(t, testdb.Options{})
+
+	first, err := Run(ctx, conn, fixtures, Options{Env: "test", FakeSeed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := []Result{{Table: "authors", Inserted: 2}, {Table: "authors", Inserted: 1}}
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("first Run() = %v, want %v", first, want)
+	}
+
+	// The second run with the same fake seed matches every record by its key
+	second, err := Run(ctx, conn, fixtures, Options{Env: "test", FakeSeed: 1})
+	if err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	want = []Result{{Table: "authors", Updated: 2}, {Table: "authors", Updated: 1}}
+	if !reflect.DeepEqual(second, want) {
+		t.Errorf("second Run() = %v, want %v", second, want)
+	}
+
+	for query, want := range map[string]string{
+		"SELECT bio FROM authors WHERE name = 'Rob Pike'": "$ref:rob.name", // the escaped value is stored as is
+		"SELECT bio FROM authors WHERE id = 100":          "Rob Pike",      // the reference is resolved
+	} {
+		var bio string
+		if err := conn.QueryRowContext(ctx, query).Scan(&bio); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if bio != want {
+			t.Errorf("%s = %q, want %q", query, bio, want)
+		}
+	}
+
+	var count int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM authors").Scan(&count); err != nil {
+		t.Fatalf("failed to count authors: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("authors count = %d, want 3", count)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		fixtures string
+		env      string
+		wantErr  error
+	}{
+		{
+			name:     "production",
+			fixtures: "[]",
+			env:      "Production",
+			wantErr:  ErrProductionEnv,
+		},
+		{
+			name:     "null key",
+			fixtures: "- table: authors\n  key: [name]\n  records:\n    - name: Rob Pike\n    - name: ~\n",
+			wantErr:  ErrInvalidFixture,
+		},
+		{
+			name:     "key matches many records",
+			fixtures: "- table: authors\n  records:\n    - {id: 1, name: Rob Pike}\n    - {id: 2, name: Rob Pike}\n- table: authors\n  key: [name]\n  records:\n    - name: Rob Pike\n",
+			wantErr:  ErrInvalidFixture,
+		},
+		{
+			name:     "duplicate reference",
+			fixtures: "- table: authors\n  records:\n    - {_ref: rob, id: 1, name: Rob Pike}\n    - {_ref: rob, id: 2, name: Ken Thompson}\n",
+			wantErr:  ErrDuplicateReference,
+		},
+		{
+			name:     "reference to a later record",
+			fixtures: "- table: authors\n  records:\n    - {id: 1, name: $ref:ken.name}\n    - {_ref: ken, id: 2, name: Ken Thompson}\n",
+			wantErr:  ErrUnknownReference,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			fixtures, err := ParseFixtures("fixtures.yaml", []byte(tt.fixtures))
+			if err != nil {
+				t.Fatalf("ParseFixtures() error = %v", err)
+			}
+			conn := testdb.Open(t, testdb.Options{})
+
+			env := tt.env
+			if env == "" {
+				env = "test"
+			}
+			if _, err := Run(ctx, conn, fixtures, Options{Env: env}); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// Nothing is committed if any record fails
+			var count int
+			if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM authors").Scan(&count); err != nil {
+				t.Fatalf("failed to count authors: %v", err)
+			}
+			if count != 0 {
+				t.Errorf("authors count = %d after the failed Run(), want 0", count)
+			}
+		})
+	}
+}
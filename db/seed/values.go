@@ -0,0 +1,168 @@
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"braces.dev/errtrace"
+)
+
+// Prefixes of the generated string values.
+const (
+	refPrefix  = "$ref:"  // $ref:<name>.<column>, a column of the referenced record
+	fakePrefix = "$fake:" // $fake:<kind>[:<args>], a generated value, see Faker
+	escPrefix  = "$$"     // $$ref:... is stored as the literal "$ref:..."
+)
+
+// Refs holds the seeded records by their reference names.
+// The values are the actual columns of the records as stored in the database,
+// e.g. the generated IDs.
+type Refs map[string]map[string]any
+
+// Get returns the column of the referenced record.
+func (r Refs) Get(name, column string) (any, error) {
+	rec, ok := r[name]
+	if !ok {
+		return nil, errtrace.Wrap(fmt.Errorf("%w: %q", ErrUnknownReference, name))
+	}
+	v, ok := rec[column]
+	if !ok {
+		return nil, errtrace.Wrap(fmt.Errorf("%w: %q has no column %q", ErrUnknownReference, name, column))
+	}
+	return v, nil
+}
+
+// Resolve returns the value to be stored:
+//   - "$ref:rob.id" is replaced with the id column of the record with the reference name "rob",
+//     the record must be seeded before, e.g. in a previous file;
+//   - "$fake:name" is replaced with a generated value, see Faker.Value;
+//   - strings starting with "$$" are stored without the first "$";
+//   - numbers of JSON files are converted to int64 or float64.
+func Resolve(v any, refs Refs, fake *Faker) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return errtrace.Wrap2(v.Float64())
+	case int:
+		return int64(v), nil
+	case string:
+		switch {
+		case strings.HasPrefix(v, escPrefix):
+			return v[1:], nil
+		case strings.HasPrefix(v, refPrefix):
+			name, column, ok := strings.Cut(strings.TrimPrefix(v, refPrefix), ".")
+			if !ok {
+				column = "id"
+			}
+			return errtrace.Wrap2(refs.Get(name, column))
+		case strings.HasPrefix(v, fakePrefix):
+			return errtrace.Wrap2(fake.Value(strings.TrimPrefix(v, fakePrefix)))
+		}
+		return v, nil
+	case nil, bool, int64, float64:
+		return v, nil
+	}
+	return nil, errtrace.Wrap(fmt.Errorf("%w: unsupported value %v of type %T", ErrInvalidFixture, v, v))
+}
+
+// Faker generates fake values. The generator is seeded,
+// so the same fixtures produce the same values on every run and the seeding stays idempotent.
+type Faker struct {
+	rnd *rand.Rand
+}
+
+// NewFaker creates a new fake values generator with the given seed.
+func NewFaker(seed int64) *Faker {
+	return &Faker{rnd: rand.New(rand.NewSource(seed))}
+}
+
+// The fake names must not produce the names of the hand-written fixtures, e.g. Rob Pike,
+// or a fake record keyed by name would overwrite them.
+var (
+	fakeFirstNames = []string{"Ada", "Alan", "Barbara", "Dennis", "Edsger", "Frances", "Grace", "Linus", "Margaret", "Niklaus", "Radia", "Robert", "Sophie", "Tim"}
+	fakeLastNames  = []string{"Allen", "Hopper", "Kernighan", "Knuth", "Lamport", "Liskov", "Lovelace", "Perlman", "Ritchie", "Torvalds", "Turing", "Wilson", "Wirth"}
+	fakeWords      = []string{"alpha", "binary", "cache", "data", "engine", "format", "graph", "hash", "index", "join", "kernel", "lambda", "memory", "network", "object", "pointer", "query", "record", "schema", "thread", "update", "vector", "write"}
+	fakeDomains    = []string{"example.com", "example.org", "example.net"}
+)
+
+// Value returns the generated value of the kind. Supported kinds:
+//   - name, first_name, last_name, username, email, phone, url, uuid, bool;
+//   - word, sentence, paragraph;
+//   - int:<min>:<max>, e.g. int:1:100, both bounds are inclusive, default 0:1000.
+func (f *Faker) Value(kind string) (any, error) {
+	kind, args, _ := strings.Cut(kind, ":")
+	switch kind {
+	case "name":
+		return f.pick(fakeFirstNames) + " " + f.pick(fakeLastNames), nil
+	case "first_name":
+		return f.pick(fakeFirstNames), nil
+	case "last_name":
+		return f.pick(fakeLastNames), nil
+	case "username":
+		return strings.ToLower(f.pick(fakeFirstNames)) + strconv.Itoa(f.rnd.Intn(1000)), nil
+	case "email":
+		return fmt.Sprintf("%s.%s%d@%s",
+			strings.ToLower(f.pick(fakeFirstNames)), strings.ToLower(f.pick(fakeLastNames)),
+			f.rnd.Intn(1000), f.pick(fakeDomains)), nil
+	case "phone":
+		return fmt.Sprintf("+1555%07d", f.rnd.Intn(10_000_000)), nil
+	case "url":
+		return fmt.Sprintf("https://%s/%s", f.pick(fakeDomains), f.pick(fakeWords)), nil
+	case "uuid":
+		b := make([]byte, 16)
+		f.rnd.Read(b)
+		b[6] = b[6]&0x0f | 0x40 // version 4
+		b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+	case "bool":
+		return f.rnd.Intn(2) == 1, nil
+	case "word":
+		return f.pick(fakeWords), nil
+	case "sentence":
+		return f.sentence(), nil
+	case "paragraph":
+		sentences := make([]string, 3+f.rnd.Intn(3))
+		for i := range sentences {
+			sentences[i] = f.sentence()
+		}
+		return strings.Join(sentences, " "), nil
+	case "int":
+		return errtrace.Wrap2(f.int(args))
+	}
+	return nil, errtrace.Wrap(fmt.Errorf("%w: %q", ErrUnknownFakeValue, kind))
+}
+
+// pick returns a random element of the list.
+func (f *Faker) pick(list []string) string {
+	return list[f.rnd.Intn(len(list))]
+}
+
+// sentence returns a capitalized sentence of random words.
+func (f *Faker) sentence() string {
+	words := make([]string, 4+f.rnd.Intn(6))
+	for i := range words {
+		words[i] = f.pick(fakeWords)
+	}
+	s := strings.Join(words, " ")
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// int returns a random integer in the range given as "<min>:<max>".
+func (f *Faker) int(args string) (int64, error) {
+	lo, hi := int64(0), int64(1000)
+	if args != "" {
+		minArg, maxArg, ok := strings.Cut(args, ":")
+		var err1, err2 error
+		lo, err1 = strconv.ParseInt(minArg, 10, 64)
+		hi, err2 = strconv.ParseInt(maxArg, 10, 64)
+		if !ok || err1 != nil || err2 != nil || hi < lo {
+			return 0, errtrace.Wrap(fmt.Errorf("%w: invalid int range %q", ErrUnknownFakeValue, args))
+		}
+	}
+	return lo + f.rnd.Int63n(hi-lo+1), nil
+}
@@ -0,0 +1,14 @@
+// Package seeds contains the fixture files of the seed command, one directory per environment,
+// e.g. local/01-authors.yaml, see seed.Fixture for the format.
+//
+// Data which needs Go logic is seeded through the repository by the functions
+// registered in this package with seed.Register from an init function:
+//
+//	func init() {
+//		seed.Register(seed.GoSeed{
+//			Name: "demo-authors",
+//			Envs: []string{config.EnvLocal},
+//			Run:  func(ctx context.Context, q repository.Querier, refs seed.Refs) error { ... },
+//		})
+//	}
+package seeds
@@ -7,4 +7,4 @@ CREATE TABLE authors (
 );
 
 -- +migrate Down
-DROP TABLE examples;
+DROP TABLE authors;
@@ -0,0 +1,18 @@
+
+-- +migrate Up
+CREATE TABLE outbox (
+  id              INTEGER  PRIMARY KEY,
+  task_name       text     NOT NULL,
+  payload         text     NOT NULL,
+  dedupe_key      text     NOT NULL UNIQUE,
+  attempts        INTEGER  NOT NULL DEFAULT 0,
+  last_error      text,
+  created_at      DATETIME NOT NULL,
+  next_attempt_at DATETIME NOT NULL,
+  published_at    DATETIME
+);
+CREATE INDEX outbox_pending_idx ON outbox (published_at, next_attempt_at);
+
+-- +migrate Down
+DROP INDEX outbox_pending_idx;
+DROP TABLE outbox;
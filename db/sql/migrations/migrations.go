@@ -0,0 +1,57 @@
+// Package migrations embeds the SQL migration files into the binary,
+// so deployed binaries don't need the files shipped alongside.
+//
+// Migrations which need Go logic, e.g. data backfills, are registered in this package
+// with migration.Register from an init function, and are run in the order of their IDs
+// together with the SQL files:
+//
+//	func init() {
+//		migration.Register(migration.GoMigration{
+//			ID: "20240301120000-split-author-names",
+//			Up: func(ctx context.Context, tx *sql.Tx, q repository.Querier) error { ... },
+//		})
+//	}
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS contains all SQL migration files of this directory:
+// SQLite migrations in the root and Postgres migrations in the postgres directory.
+// Both sets must describe the same schema, see the README.
+//
+//go:embed *.sql postgres/*.sql
+var FS embed.FS
+
+// postgresDir is the directory of the Postgres migrations.
+const postgresDir = "postgres"
+
+// Source returns the migrations file system for the SQL dialect, e.g. "sqlite3" or "postgres".
+// If dir is not empty, the migrations are read from the directory on disk,
+// otherwise the embedded migrations of the dialect are used.
+// The directory has the same layout as the embedded one, so for Postgres its postgres
+// subdirectory is used if it exists, e.g. for ./db/sql/migrations.
+func Source(dialect, dir string) fs.FS {
+	if dir != "" {
+		if dialect == "postgres" {
+			sub := filepath.Join(dir, postgresDir)
+			if info, err := os.Stat(sub); err == nil && info.IsDir() {
+				return os.DirFS(sub)
+			}
+		}
+		return os.DirFS(dir)
+	}
+	if dialect == "postgres" {
+		sub, err := fs.Sub(FS, postgresDir)
+		if err != nil {
+			// fs.Sub fails only for an invalid path
+			panic(err)
+		}
+		return sub
+	}
+	return FS
+}
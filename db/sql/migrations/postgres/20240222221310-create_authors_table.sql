@@ -0,0 +1,10 @@
+
+-- +migrate Up
+CREATE TABLE authors (
+  id   BIGSERIAL PRIMARY KEY,
+  name text      NOT NULL,
+  bio  text
+);
+
+-- +migrate Down
+DROP TABLE authors;
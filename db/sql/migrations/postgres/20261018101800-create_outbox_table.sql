@@ -0,0 +1,18 @@
+
+-- +migrate Up
+CREATE TABLE outbox (
+  id              BIGSERIAL   PRIMARY KEY,
+  task_name       text        NOT NULL,
+  payload         text        NOT NULL,
+  dedupe_key      text        NOT NULL UNIQUE,
+  attempts        BIGINT      NOT NULL DEFAULT 0,
+  last_error      text,
+  created_at      TIMESTAMPTZ NOT NULL,
+  next_attempt_at TIMESTAMPTZ NOT NULL,
+  published_at    TIMESTAMPTZ
+);
+CREATE INDEX outbox_pending_idx ON outbox (published_at, next_attempt_at);
+
+-- +migrate Down
+DROP INDEX outbox_pending_idx;
+DROP TABLE outbox;
@@ -0,0 +1,32 @@
+-- name: CreateOutboxMessage :one
+-- Returns no rows if a message with the same dedupe key exists.
+INSERT INTO outbox (
+  task_name, payload, dedupe_key, created_at, next_attempt_at
+) VALUES (
+  ?, ?, ?, ?, ?
+)
+ON CONFLICT (dedupe_key) DO NOTHING
+RETURNING *;
+
+-- name: ListPendingOutboxMessages :many
+SELECT * FROM outbox
+WHERE published_at IS NULL AND next_attempt_at <= @now
+ORDER BY id
+LIMIT @limit;
+
+-- name: MarkOutboxMessagePublished :exec
+UPDATE outbox
+SET published_at = @published_at,
+last_error = NULL
+WHERE id = @id;
+
+-- name: MarkOutboxMessageFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1,
+last_error = @last_error,
+next_attempt_at = @next_attempt_at
+WHERE id = @id;
+
+-- name: DeletePublishedOutboxMessages :execrows
+DELETE FROM outbox
+WHERE published_at IS NOT NULL AND published_at < @before;
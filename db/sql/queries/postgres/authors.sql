@@ -0,0 +1,25 @@
+-- name: GetAuthor :one
+SELECT * FROM authors
+WHERE id = $1 LIMIT 1;
+
+-- name: ListAuthors :many
+SELECT * FROM authors
+ORDER BY name;
+
+-- name: CreateAuthor :one
+INSERT INTO authors (
+  name, bio
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: UpdateAuthor :exec
+UPDATE authors
+set name = @name,
+bio = @bio
+WHERE id = @id;
+
+-- name: DeleteAuthor :exec
+DELETE FROM authors
+WHERE id = $1;
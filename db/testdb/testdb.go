@@ -0,0 +1,223 @@
+// Package testdb provides isolated, migrated databases for tests,
+// so the repository and the handlers can be tested without a running database server.
+//
+// Each call opens a new in-memory libSQL database with all migrations applied,
+// including the Go migrations registered in db/sql/migrations,
+// and closes it when the test finishes. Databases are not shared between tests,
+// so the tests can run with t.Parallel and go test -race:
+//
+//	func TestAuthors(t *testing.T) {
+//		t.Parallel()
+//		q := testdb.New(t, testdb.Options{
+//			Fixtures: []testdb.Fixture{testdb.FixtureFiles(os.DirFS("testdata"), "authors.sql")},
+//		})
+//		...
+//	}
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db"
+	libsql_local "github.com/dmitrymomot/go-app-template/db/libsql/local"
+	"github.com/dmitrymomot/go-app-template/db/migration"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+	"github.com/dmitrymomot/go-app-template/db/sql/migrations"
+)
+
+// Fixture loads test data into the database, e.g. with repository.New(conn) or raw inserts.
+type Fixture func(ctx context.Context, conn repository.DBTX) error
+
+// Options defines the test database settings.
+type Options struct {
+	// Tx binds the repository to a transaction which is rolled back when the test finishes,
+	// so the changes made by the test, including the fixtures, are never committed.
+	Tx bool
+	// Fixtures are loaded in order after the migrations are applied.
+	Fixtures []Fixture
+}
+
+// New returns the repository bound to a new migrated in-memory database,
+// or to a transaction in it if opts.Tx is set. The test fails if the database can't be prepared.
+func New(t testing.TB, opts Options) *repository.Queries {
+	t.Helper()
+
+	return bind(t, Open(t, Options{}), opts)
+}
+
+// bind loads the fixtures and returns the repository bound to the database,
+// or to a transaction which is rolled back when the test finishes if opts.Tx is set.
+func bind(t testing.TB, conn *sql.DB, opts Options) *repository.Queries {
+	t.Helper()
+
+	if !opts.Tx {
+		load(t, conn, opts.Fixtures)
+		return repository.New(conn)
+	}
+
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("testdb: failed to begin transaction: %v", err)
+	}
+	// Cleanup functions run in the reverse order, so the transaction is rolled back before the database is closed
+	t.Cleanup(func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("testdb: failed to roll back transaction: %v", err)
+		}
+	})
+
+	load(t, tx, opts.Fixtures)
+	return repository.New(tx)
+}
+
+// Open returns a new migrated in-memory database with the fixtures loaded,
+// e.g. for the code which runs its own transactions with db.RunInTx.
+// The database is closed when the test finishes. opts.Tx is ignored.
+func Open(t testing.TB, opts Options) *sql.DB {
+	t.Helper()
+
+	// An empty connection string opens an in-memory database with a single connection,
+	// every database is private to the test
+	conn, err := libsql_local.Connect("")
+	if err != nil {
+		t.Fatalf("testdb: failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := conn.Close(); err != nil {
+			t.Errorf("testdb: failed to close database: %v", err)
+		}
+	})
+
+	if err := migrate(context.Background(), conn); err != nil {
+		t.Fatalf("testdb: failed to apply migrations: %v", err)
+	}
+
+	load(t, conn, opts.Fixtures)
+	return conn
+}
+
+// FixtureFiles returns the fixture which executes the SQL files matching the patterns,
+// in the lexical order of their names. The patterns use the fs.Glob syntax, e.g. "*.sql".
+// A file may contain several statements separated by semicolons, they are executed one by one,
+// as libSQL executes only the first statement of a multi-statement query.
+func FixtureFiles(fsys fs.FS, patterns ...string) Fixture {
+	return func(ctx context.Context, conn repository.DBTX) error {
+		var files []string
+		for _, pattern := range patterns {
+			matches, err := fs.Glob(fsys, pattern)
+			if err != nil {
+				return errtrace.Wrap(err)
+			}
+			if len(matches) == 0 {
+				return errtrace.Wrap(errors.Join(fs.ErrNotExist, errors.New(pattern)))
+			}
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+
+		for _, name := range files {
+			script, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return errtrace.Wrap(err)
+			}
+			for _, stmt := range splitStatements(string(script)) {
+				if _, err := conn.ExecContext(ctx, stmt); err != nil {
+					return errtrace.Wrap(errors.Join(errors.New(name), err))
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// migrate applies all migrations embedded into the binary.
+func migrate(ctx context.Context, conn *sql.DB) error {
+	dialect := string(db.DialectSQLite)
+	m, err := migration.NewMigratorFS(conn, dialect, "", migrations.Source(dialect, ""))
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	_, err = m.Up(ctx, 0)
+	return errtrace.Wrap(err)
+}
+
+// load loads the fixtures, the test fails on the first error.
+func load(t testing.TB, conn repository.DBTX, fixtures []Fixture) {
+	t.Helper()
+
+	for i, fixture := range fixtures {
+		if err := fixture(context.Background(), conn); err != nil {
+			t.Fatalf("testdb: failed to load fixture #%d: %v", i, err)
+		}
+	}
+}
+
+// splitStatements splits the SQL script into statements by semicolons
+// outside of string literals, quoted identifiers and comments.
+// Statements with nested semicolons, e.g. CREATE TRIGGER, are not supported.
+func splitStatements(script string) []string {
+	var (
+		result []string
+		start  int
+		quote  byte
+	)
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+		case c == ';':
+			result = appendStatement(result, script[start:i])
+			start = i + 1
+		}
+	}
+	return appendStatement(result, script[min(start, len(script)):])
+}
+
+// appendStatement appends the statement unless it's empty or consists of comments only.
+func appendStatement(list []string, stmt string) []string {
+	stmt = strings.TrimSpace(stmt)
+	rest := stmt
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "--"):
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				rest = strings.TrimSpace(rest[end:])
+			} else {
+				rest = ""
+			}
+		case strings.HasPrefix(rest, "/*"):
+			if end := strings.Index(rest, "*/"); end >= 0 {
+				rest = strings.TrimSpace(rest[end+2:])
+			} else {
+				rest = ""
+			}
+		default:
+			return append(list, stmt)
+		}
+	}
+	return list
+}
@@ -0,0 +1,155 @@
+package testdb
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dmitrymomot/go-app-template/db/repository"
+)
+
+func TestNewTxRollback(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	conn := Open(t, Options{})
+	fixture := FixtureFiles(fstest.MapFS{"authors.sql": {Data: []byte("INSERT INTO authors (name) VALUES ('Ken Thompson');")}}, "*.sql")
+
+	// The transaction is rolled back by the cleanup of the subtest
+	t.Run("tx", func(t *testing.T) {
+		q := bind(t, conn, Options{Tx: true, Fixtures: []Fixture{fixture}})
+		if _, err := q.CreateAuthor(ctx, repository.CreateAuthorParams{Name: "Rob Pike"}); err != nil {
+			t.Fatalf("CreateAuthor() error = %v", err)
+		}
+		authors, err := q.ListAuthors(ctx)
+		if err != nil || len(authors) != 2 {
+			t.Fatalf("ListAuthors() = %d authors, %v, want the fixture and the created author in the transaction", len(authors), err)
+		}
+	})
+
+	authors, err := repository.New(conn).ListAuthors(ctx)
+	if err != nil {
+		t.Fatalf("ListAuthors() error = %v", err)
+	}
+	if len(authors) != 0 {
+		t.Errorf("ListAuthors() after the test returned %d authors, want the transaction rolled back", len(authors))
+	}
+}
+
+func TestNewIsolation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	first := New(t, Options{})
+	if _, err := first.CreateAuthor(ctx, repository.CreateAuthorParams{Name: "Rob Pike"}); err != nil {
+		t.Fatalf("CreateAuthor() error = %v", err)
+	}
+
+	authors, err := New(t, Options{}).ListAuthors(ctx)
+	if err != nil {
+		t.Fatalf("ListAuthors() error = %v", err)
+	}
+	if len(authors) != 0 {
+		t.Errorf("ListAuthors() returned %d authors, want the databases not shared", len(authors))
+	}
+}
+
+func TestFixtureFiles(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	fsys := fstest.MapFS{
+		"02-bio.sql":     {Data: []byte("UPDATE authors SET bio = 'Go' WHERE name = 'Rob Pike';")},
+		"01-authors.sql": {Data: []byte("INSERT INTO authors (name) VALUES ('Rob Pike');\nINSERT INTO authors (name) VALUES ('Ken Thompson');")},
+		"README.md":      {Data: []byte("not a fixture")},
+	}
+
+	// The bio update fails silently if the files are not executed in the lexical order
+	q := New(t, Options{Fixtures: []Fixture{FixtureFiles(fsys, "02-*.sql", "01-*.sql")}})
+	authors, err := q.ListAuthors(ctx)
+	if err != nil {
+		t.Fatalf("ListAuthors() error = %v", err)
+	}
+	if len(authors) != 2 {
+		t.Fatalf("ListAuthors() returned %d authors, want 2", len(authors))
+	}
+	for _, a := range authors {
+		if a.Name == "Rob Pike" && a.Bio.String != "Go" {
+			t.Errorf("Bio of %q = %+v, want the update of the later file applied", a.Name, a.Bio)
+		}
+	}
+}
+
+func TestFixtureFilesMissingPattern(t *testing.T) {
+	t.Parallel()
+
+	conn := Open(t, Options{})
+	fixture := FixtureFiles(fstest.MapFS{"01-authors.sql": {Data: []byte("SELECT 1;")}}, "*.sql", "missing/*.sql")
+	if err := fixture(context.Background(), conn); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("FixtureFiles() error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "single statement without semicolon",
+			script: "SELECT 1",
+			want:   []string{"SELECT 1"},
+		},
+		{
+			name:   "several statements",
+			script: "SELECT 1;\nSELECT 2;\n",
+			want:   []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:   "semicolons in quotes",
+			script: `INSERT INTO t VALUES ('a;b'); SELECT "c;d", ` + "`e;f`" + `;`,
+			want:   []string{`INSERT INTO t VALUES ('a;b')`, `SELECT "c;d", ` + "`e;f`"},
+		},
+		{
+			name:   "escaped quote",
+			script: `SELECT 'it''s; fine'; SELECT 2`,
+			want:   []string{`SELECT 'it''s; fine'`, `SELECT 2`},
+		},
+		{
+			name:   "line comments",
+			script: "-- header; not a statement\nSELECT 1; -- trailing; comment\nSELECT 2;",
+			want:   []string{"-- header; not a statement\nSELECT 1", "-- trailing; comment\nSELECT 2"},
+		},
+		{
+			name:   "block comments",
+			script: "/* a; b */ SELECT 1; /* only a comment; */",
+			want:   []string{"/* a; b */ SELECT 1"},
+		},
+		{
+			name:   "comments only",
+			script: "-- nothing here;\n/* or here; */\n",
+			want:   nil,
+		},
+		{
+			name:   "empty statements",
+			script: ";; SELECT 1;;",
+			want:   []string{"SELECT 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
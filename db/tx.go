@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/db/dberr"
+	"github.com/dmitrymomot/go-app-template/db/repository"
+)
+
+// Default retry settings of RunInTx.
+const (
+	defaultTxMaxAttempts = 5
+	defaultTxBackoff     = 20 * time.Millisecond
+	defaultTxMaxBackoff  = time.Second
+)
+
+// TxFunc is the function executed in a transaction by RunInTx.
+// The context carries the transaction, pass it to nested RunInTx and AfterCommit calls.
+type TxFunc func(ctx context.Context, q repository.Querier) error
+
+// TxOptions defines the transaction settings used by RunInTx.
+// The Dialect must be set, the zero values of the other fields mean the default isolation level
+// of the driver and the default retry settings.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxAttempts is the maximum number of attempts including the first one,
+	// 1 disables retries. Default: 5.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, it's doubled on each next retry
+	// up to MaxBackoff. A random jitter is added to the delay. Defaults: 20ms and 1s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Dialect selects the repository passed to TxFunc, see NewQuerier. Required:
+	// the repository of another dialect would fail on every query.
+	Dialect Dialect
+	// Wrap wraps the transaction before it's passed to NewQuerier,
+	// e.g. with the instrument package. It's inherited by nested calls.
+	Wrap func(repository.DBTX) repository.DBTX
+	// WrapQuerier wraps the repository passed to TxFunc, e.g. with cache.Querier.WithTx
+	// to invalidate the cached data after the commit. It's inherited by nested calls.
+	WrapQuerier func(repository.Querier) repository.Querier
+}
+
+// txKey is the context key of the current transaction state.
+type txKey struct{}
+
+// txState is the state of the outermost transaction shared by nested calls.
+type txState struct {
+	tx    *sql.Tx
+	q     repository.Querier
+	depth int
+	hooks []func(context.Context)
+}
+
+// RunInTx executes fn in a database transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise,
+// including the case when fn panics, the panic is propagated after the rollback.
+//
+// The whole transaction is retried with exponential backoff if it fails with a retryable error:
+// SQLITE_BUSY or a Postgres serialization failure or deadlock. So fn must be safe to run several times,
+// side effects must be registered with AfterCommit.
+//
+// Nested calls with the context passed to fn are mapped to savepoints of the outer transaction:
+// an error of the nested fn rolls back only its own changes. The options of nested calls are ignored.
+func RunInTx(ctx context.Context, db *sql.DB, opts TxOptions, fn TxFunc) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return errtrace.Wrap(runInSavepoint(ctx, state, fn))
+	}
+
+	if opts.Dialect == "" {
+		return errtrace.Wrap(ErrUndefinedDialect)
+	}
+
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultTxMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultTxBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultTxMaxBackoff
+	}
+
+	backoff := opts.Backoff
+	for attempt := 1; ; attempt++ {
+		state, err := runTx(ctx, db, opts, fn)
+		if err == nil {
+			// The transaction runs on the primary, so the following reads of the request
+			// must go there too, see WithReadYourWrites
+			if !opts.ReadOnly {
+				markWritten(ctx)
+			}
+			for _, hook := range state.hooks {
+				hook(ctx)
+			}
+			return nil
+		}
+		if attempt >= opts.MaxAttempts || !IsRetryable(err) {
+			return errtrace.Wrap(err)
+		}
+
+		// Random jitter spreads the retries of concurrent transactions
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return errtrace.Wrap(errors.Join(err, ctx.Err()))
+		case <-time.After(delay):
+		}
+		if backoff *= 2; backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+}
+
+// runTx makes a single attempt to execute fn in a transaction.
+func runTx(ctx context.Context, db *sql.DB, opts TxOptions, fn TxFunc) (*txState, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToBeginTx, err))
+	}
+
+	var dbtx repository.DBTX = tx
+	if opts.Wrap != nil {
+		dbtx = opts.Wrap(tx)
+	}
+	state := &txState{tx: tx, q: NewQuerier(opts.Dialect, dbtx)}
+	if opts.WrapQuerier != nil {
+		state.q = opts.WrapQuerier(state.q)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, state), state.q); err != nil {
+		if rerr := tx.Rollback(); rerr != nil && !errors.Is(rerr, sql.ErrTxDone) {
+			err = errors.Join(err, ErrFailedToRollbackTx, rerr)
+		}
+		return nil, errtrace.Wrap(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errtrace.Wrap(errors.Join(ErrFailedToCommitTx, err))
+	}
+
+	return state, nil
+}
+
+// runInSavepoint executes fn within a savepoint of the current transaction.
+func runInSavepoint(ctx context.Context, state *txState, fn TxFunc) error {
+	state.depth++
+	name := fmt.Sprintf("sp_%d", state.depth)
+	hooks := len(state.hooks)
+	defer func() { state.depth-- }()
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return errtrace.Wrap(errors.Join(ErrFailedToCreateSavepoint, err))
+	}
+
+	// rollback discards the changes and the hooks registered since the savepoint.
+	// ROLLBACK TO keeps the savepoint open, so it's released to not stack up in the transaction.
+	rollback := func() error {
+		state.hooks = state.hooks[:hooks]
+		if _, err := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); err != nil {
+			return err
+		}
+		_, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx, state.q); err != nil {
+		if rerr := rollback(); rerr != nil {
+			err = errors.Join(err, ErrFailedToRollbackTx, rerr)
+		}
+		return errtrace.Wrap(err)
+	}
+
+	if _, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return errtrace.Wrap(errors.Join(ErrFailedToReleaseSavepoint, err))
+	}
+
+	return nil
+}
+
+// AfterCommit registers the hook to be called after the transaction carried by ctx is committed,
+// e.g. to enqueue an email. Hooks are called in the order of registration and discarded
+// if the transaction or the savepoint they were registered in is rolled back.
+// Without a transaction in ctx the hook is called immediately.
+func AfterCommit(ctx context.Context, hook func(ctx context.Context)) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		hook(ctx)
+		return
+	}
+	state.hooks = append(state.hooks, hook)
+}
+
+// TxFromContext returns the transaction carried by the context passed to TxFunc.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	return state.tx, true
+}
+
+// IsRetryable reports whether the transaction failed because of concurrent access
+// and can be retried: SQLite busy or locked database, Postgres serialization failure or deadlock.
+func IsRetryable(err error) bool {
+	return dberr.Is(err, dberr.ErrBusy)
+}
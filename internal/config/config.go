@@ -0,0 +1,266 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"braces.dev/errtrace"
+)
+
+// Enviroments
+const (
+	EnvLocal       = "local"
+	EnvDevelopment = "development"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+	EnvTesting     = "testing"
+)
+
+// Config is the application configuration shared by all commands.
+type Config struct {
+	App        App
+	DB         DB
+	Migrations Migrations
+	HTTP       HTTP
+	CORS       CORS
+	CSRF       CSRF
+	Static     Static
+	Redis      Redis
+	QueryCache QueryCache
+	Outbox     Outbox
+	Session    Session
+	Postmark   Postmark
+	Email      Email
+}
+
+// App holds the general application settings.
+type App struct {
+	Name      string // APP_NAME
+	Env       string // APP_ENV: local, development, staging, production, testing
+	DebugMode bool   // APP_DEBUG_MODE
+	LogLevel  string // APP_LOG_LEVEL: debug, info, warn, error
+	BuildTag  string // COMMIT_HASH
+}
+
+// DB holds the database connection settings.
+type DB struct {
+	URL          string // DATABASE_URL
+	MaxOpenConns int    // DATABASE_MAX_OPEN_CONNS
+	MaxIdleConns int    // DATABASE_IDLE_CONNS
+	// DATABASE_CONN_MAX_LIFETIME, connections older than this are closed, 0 means no limit
+	ConnMaxLifetime time.Duration
+	// DATABASE_CONN_MAX_IDLE_TIME, idle connections are closed after this time, 0 means no limit
+	ConnMaxIdleTime time.Duration
+	// DATABASE_CONNECT_TIMEOUT, how long to wait for the database to become reachable on startup
+	ConnectTimeout time.Duration
+	// DATABASE_READ_REPLICA_URLS, comma-separated read replicas of the primary database, reads are sent to them
+	ReadReplicaURLs []string
+	// DATABASE_SLOW_QUERY_THRESHOLD, queries running longer are logged, 0 disables the log
+	SlowQueryThreshold time.Duration
+	// DATABASE_NPLUSONE_THRESHOLD, in debug mode warn when a request runs the same query more times, 0 disables it
+	NPlusOneThreshold int
+	ReplicaPath       string        // DATABASE_REPLICA_PATH, enables embedded replica of the remote libSQL database
+	SyncInterval      time.Duration // DATABASE_SYNC_INTERVAL, embedded replica sync interval, 0 disables periodic sync
+}
+
+// Migrations holds the database migrations settings.
+type Migrations struct {
+	Dir         string        // DATABASE_MIGRATIONS_DIR, empty means the migrations embedded into the binary
+	Table       string        // DATABASE_MIGRATIONS_TABLE
+	AutoMigrate bool          // AUTO_MIGRATE, apply pending migrations on the app startup
+	LockTimeout time.Duration // DATABASE_MIGRATIONS_LOCK_TIMEOUT, how long to wait for other instances to finish migrations
+	SchemaFile  string        // DATABASE_SCHEMA_FILE, schema snapshot written by the migrate command, empty disables it
+}
+
+// HTTP holds the HTTP server settings.
+type HTTP struct {
+	Port            int           // HTTP_PORT
+	ServerHeader    string        // SERVER_HEADER
+	RequestTimeout  time.Duration // HTTP_REQUEST_TIMEOUT
+	ThrottleLimit   int           // HTTP_TROTTLE_LIMIT
+	ThrottleBacklog int           // HTTP_TROTTLE_BACKLOG
+	ThrottleTimeout time.Duration // HTTP_TROTTLE_TIMEOUT
+	RequestLimit    int           // HTTP_REQUEST_LIMIT
+	RateLimitWindow time.Duration // HTTP_RATE_LIMIT_WINDOW
+	ReadTimeout     time.Duration // HTTP_READ_TIMEOUT
+	WriteTimeout    time.Duration // HTTP_WRITE_TIMEOUT
+	BodyLimit       int64         // HTTP_BODY_LIMIT, max size of the request body in bytes
+	DisableCache    bool          // DISABLE_HTTP_CACHE
+	MetricsPort     int           // HTTP_METRICS_PORT, internal port of the /debug/vars metrics, 0 disables it
+}
+
+// CORS holds the cross-origin resource sharing settings.
+type CORS struct {
+	AllowedOrigins   []string // CORS_ALLOWED_ORIGINS
+	AllowedMethods   []string // CORS_ALLOWED_METHODS
+	AllowedHeaders   []string // CORS_ALLOWED_HEADERS
+	AllowCredentials bool     // CORS_ALLOWED_CREDENTIALS
+	MaxAge           int      // CORS_MAX_AGE
+}
+
+// CSRF holds the CSRF protection settings.
+type CSRF struct {
+	Secret []byte // CSRF_SECRET
+}
+
+// Static holds the static file serving settings.
+type Static struct {
+	Dir       string        // STATIC_DIR, must be a relative path
+	URLPrefix string        // STATIC_URL_PREFIX, must start with a slash
+	CacheTTL  time.Duration // STATIC_CACHE_TTL
+}
+
+// Redis holds the redis connection settings.
+type Redis struct {
+	URL string // REDIS_URL
+}
+
+// QueryCache holds the settings of the repository read-through cache.
+type QueryCache struct {
+	Enabled   bool          // QUERY_CACHE_ENABLED
+	Prefix    string        // QUERY_CACHE_PREFIX, prefix of the redis keys
+	LocalSize int           // QUERY_CACHE_LOCAL_SIZE, entries of the in-process LRU, 0 disables it
+	LocalTTL  time.Duration // QUERY_CACHE_LOCAL_TTL, max age of the in-process entries
+}
+
+// Outbox holds the settings of the relay publishing the outbox tasks to the queue.
+type Outbox struct {
+	PollInterval time.Duration // OUTBOX_POLL_INTERVAL
+	BatchSize    int           // OUTBOX_BATCH_SIZE, messages published per query
+	MaxBackoff   time.Duration // OUTBOX_MAX_BACKOFF, max delay between retries of a failed message
+	Retention    time.Duration // OUTBOX_RETENTION, published messages are deleted after it
+}
+
+// Session holds the session cookie settings.
+type Session struct {
+	CookieName   string        // SESSION_COOKIE_NAME
+	CookieSecure bool          // SESSION_COOKIE_SECURE, also applies to the CSRF cookie
+	Prefix       string        // SESSION_PREFIX
+	TTL          time.Duration // SESSION_TTL
+}
+
+// Postmark holds the Postmark API credentials.
+type Postmark struct {
+	ServerToken  string // POSTMARK_SERVER_TOKEN
+	AccountToken string // POSTMARK_ACCOUNT_TOKEN
+}
+
+// Email holds the outgoing email settings.
+type Email struct {
+	From    string // EMAIL_FROM
+	ReplyTo string // EMAIL_REPLY_TO
+}
+
+// Load reads the configuration from the environment and validates all its sections.
+// The returned error lists every problem found, not just the first one.
+func Load() (Config, error) {
+	cfg, err := Read()
+	if verr := cfg.Validate(); verr != nil {
+		err = errors.Join(err, verr)
+	}
+	if err != nil {
+		return cfg, errtrace.Wrap(errors.Join(ErrInvalidConfig, err))
+	}
+	return cfg, nil
+}
+
+// Read reads the configuration from the environment without validating it.
+// It returns an error only if some of the variables are set but cannot be parsed.
+// Commands that need just a part of the configuration can use Read and
+// validate the required sections on their own.
+func Read() (Config, error) {
+	e := &envReader{}
+
+	var cfg Config
+
+	// App
+	cfg.App.Name = e.String("APP_NAME", "go-app-template")
+	cfg.App.Env = e.String("APP_ENV", EnvProduction)
+	cfg.App.DebugMode = e.Bool("APP_DEBUG_MODE", false)
+	cfg.App.LogLevel = e.String("APP_LOG_LEVEL", "info")
+	cfg.App.BuildTag = e.String("COMMIT_HASH", "undefined")
+
+	// DB
+	cfg.DB.URL = e.String("DATABASE_URL", "")
+	cfg.DB.MaxOpenConns = e.Int("DATABASE_MAX_OPEN_CONNS", 20)
+	cfg.DB.MaxIdleConns = e.Int("DATABASE_IDLE_CONNS", 2)
+	cfg.DB.ConnMaxLifetime = e.Duration("DATABASE_CONN_MAX_LIFETIME", 30*time.Minute)
+	cfg.DB.ConnMaxIdleTime = e.Duration("DATABASE_CONN_MAX_IDLE_TIME", 5*time.Minute)
+	cfg.DB.ConnectTimeout = e.Duration("DATABASE_CONNECT_TIMEOUT", 30*time.Second)
+	cfg.DB.ReadReplicaURLs = e.Strings("DATABASE_READ_REPLICA_URLS", ",", nil)
+	cfg.DB.SlowQueryThreshold = e.Duration("DATABASE_SLOW_QUERY_THRESHOLD", 200*time.Millisecond)
+	cfg.DB.NPlusOneThreshold = e.Int("DATABASE_NPLUSONE_THRESHOLD", 10)
+	cfg.DB.ReplicaPath = e.String("DATABASE_REPLICA_PATH", "")
+	cfg.DB.SyncInterval = e.Duration("DATABASE_SYNC_INTERVAL", time.Minute)
+
+	// Migrations
+	cfg.Migrations.Dir = e.String("DATABASE_MIGRATIONS_DIR", "")
+	cfg.Migrations.Table = e.String("DATABASE_MIGRATIONS_TABLE", "migrations")
+	cfg.Migrations.AutoMigrate = e.Bool("AUTO_MIGRATE", false)
+	cfg.Migrations.LockTimeout = e.Duration("DATABASE_MIGRATIONS_LOCK_TIMEOUT", time.Minute)
+	cfg.Migrations.SchemaFile = e.String("DATABASE_SCHEMA_FILE", "")
+
+	// HTTP
+	cfg.HTTP.Port = e.Int("HTTP_PORT", 8080)
+	cfg.HTTP.ServerHeader = e.String("SERVER_HEADER", cfg.App.Name+"/"+cfg.App.BuildTag)
+	cfg.HTTP.RequestTimeout = e.Duration("HTTP_REQUEST_TIMEOUT", 5*time.Second)
+	cfg.HTTP.ThrottleLimit = e.Int("HTTP_TROTTLE_LIMIT", 1000)
+	cfg.HTTP.ThrottleBacklog = e.Int("HTTP_TROTTLE_BACKLOG", 1000)
+	cfg.HTTP.ThrottleTimeout = e.Duration("HTTP_TROTTLE_TIMEOUT", time.Second)
+	cfg.HTTP.RequestLimit = e.Int("HTTP_REQUEST_LIMIT", 100)
+	cfg.HTTP.RateLimitWindow = e.Duration("HTTP_RATE_LIMIT_WINDOW", time.Minute)
+	cfg.HTTP.ReadTimeout = e.Duration("HTTP_READ_TIMEOUT", 5*time.Second)
+	cfg.HTTP.WriteTimeout = e.Duration("HTTP_WRITE_TIMEOUT", 10*time.Second)
+	cfg.HTTP.BodyLimit = int64(e.Int("HTTP_BODY_LIMIT", 4*1024*1024)) // 4MB
+	cfg.HTTP.DisableCache = e.Bool("DISABLE_HTTP_CACHE", true)
+	cfg.HTTP.MetricsPort = e.Int("HTTP_METRICS_PORT", 9090)
+
+	// CORS
+	cfg.CORS.AllowedOrigins = e.Strings("CORS_ALLOWED_ORIGINS", ",", []string{"*"})
+	cfg.CORS.AllowedMethods = e.Strings("CORS_ALLOWED_METHODS", ",", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "HEAD"})
+	cfg.CORS.AllowedHeaders = e.Strings("CORS_ALLOWED_HEADERS", ",", []string{"*"})
+	cfg.CORS.AllowCredentials = e.Bool("CORS_ALLOWED_CREDENTIALS", true)
+	cfg.CORS.MaxAge = e.Int("CORS_MAX_AGE", 300)
+
+	// CSRF
+	cfg.CSRF.Secret = e.Bytes("CSRF_SECRET", []byte("development-only-csrf-key-32byte"))
+
+	// Static
+	cfg.Static.Dir = e.String("STATIC_DIR", "./web/static")
+	cfg.Static.URLPrefix = e.String("STATIC_URL_PREFIX", "/static")
+	cfg.Static.CacheTTL = e.Duration("STATIC_CACHE_TTL", time.Hour)
+
+	// Redis
+	cfg.Redis.URL = e.String("REDIS_URL", "redis://localhost:6379/0")
+
+	// Query cache
+	cfg.QueryCache.Enabled = e.Bool("QUERY_CACHE_ENABLED", true)
+	cfg.QueryCache.Prefix = e.String("QUERY_CACHE_PREFIX", "querier:")
+	cfg.QueryCache.LocalSize = e.Int("QUERY_CACHE_LOCAL_SIZE", 1024)
+	cfg.QueryCache.LocalTTL = e.Duration("QUERY_CACHE_LOCAL_TTL", 10*time.Second)
+
+	// Outbox
+	cfg.Outbox.PollInterval = e.Duration("OUTBOX_POLL_INTERVAL", time.Second)
+	cfg.Outbox.BatchSize = e.Int("OUTBOX_BATCH_SIZE", 100)
+	cfg.Outbox.MaxBackoff = e.Duration("OUTBOX_MAX_BACKOFF", time.Hour)
+	cfg.Outbox.Retention = e.Duration("OUTBOX_RETENTION", 7*24*time.Hour)
+
+	// Session
+	cfg.Session.CookieName = e.String("SESSION_COOKIE_NAME", "session")
+	cfg.Session.CookieSecure = e.Bool("SESSION_COOKIE_SECURE", isProductionLike(cfg.App.Env))
+	cfg.Session.Prefix = e.String("SESSION_PREFIX", "session:")
+	cfg.Session.TTL = e.Duration("SESSION_TTL", 24*time.Hour)
+
+	// Postmark
+	cfg.Postmark.ServerToken = e.String("POSTMARK_SERVER_TOKEN", "")
+	cfg.Postmark.AccountToken = e.String("POSTMARK_ACCOUNT_TOKEN", "")
+
+	// Email
+	cfg.Email.From = e.String("EMAIL_FROM", "notifications@localhost")
+	cfg.Email.ReplyTo = e.String("EMAIL_REPLY_TO", "no-reply@localhost")
+
+	if len(e.errs) > 0 {
+		return cfg, errtrace.Wrap(errors.Join(e.errs...))
+	}
+	return cfg, nil
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envReader reads typed values from the environment.
+// Unlike the go-env helpers it does not silently fall back to the default
+// when a value is set but malformed: every parse error is collected, so that
+// all problems can be reported at once.
+type envReader struct {
+	errs []error
+}
+
+// value returns the raw value of the variable and whether it is set and not empty.
+func (r *envReader) value(key string) (string, bool) {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return "", false
+	}
+	v = strings.TrimSpace(v)
+	return v, v != ""
+}
+
+func (r *envReader) String(key, fallback string) string {
+	if v, ok := r.value(key); ok {
+		return v
+	}
+	return fallback
+}
+
+func (r *envReader) Bytes(key string, fallback []byte) []byte {
+	if v, ok := r.value(key); ok {
+		return []byte(v)
+	}
+	return fallback
+}
+
+func (r *envReader) Bool(key string, fallback bool) bool {
+	v, ok := r.value(key)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		r.errs = append(r.errs, fieldErr(key, "must be a boolean, got %q", v))
+		return fallback
+	}
+	return b
+}
+
+func (r *envReader) Int(key string, fallback int) int {
+	v, ok := r.value(key)
+	if !ok {
+		return fallback
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		r.errs = append(r.errs, fieldErr(key, "must be an integer, got %q", v))
+		return fallback
+	}
+	return i
+}
+
+func (r *envReader) Duration(key string, fallback time.Duration) time.Duration {
+	v, ok := r.value(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		r.errs = append(r.errs, fieldErr(key, "must be a duration (e.g. 5s, 1m, 1h), got %q", v))
+		return fallback
+	}
+	return d
+}
+
+func (r *envReader) Strings(key, sep string, fallback []string) []string {
+	v, ok := r.value(key)
+	if !ok {
+		return fallback
+	}
+	result := make([]string, 0)
+	for _, s := range strings.Split(v, sep) {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	if len(result) == 0 {
+		return fallback
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Predefined errors.
+var (
+	ErrInvalidConfig = errors.New("invalid configuration")
+	ErrUnsafeConfig  = errors.New("unsafe configuration")
+)
+
+// FieldError describes a problem with a single environment variable.
+type FieldError struct {
+	Key     string
+	Message string
+}
+
+// Error implements the error interface.
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// fieldErr is a shortcut to build a FieldError with a formatted message.
+func fieldErr(key, format string, args ...interface{}) error {
+	return FieldError{Key: key, Message: fmt.Sprintf(format, args...)}
+}
+
+// UnsafeConfigError is returned by CheckSafety when the configuration is not safe
+// to run in a production-like environment.
+type UnsafeConfigError struct {
+	Env      string
+	Problems []error
+}
+
+// Error returns the report with all found problems.
+func (e *UnsafeConfigError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "refusing to start in %q environment with insecure configuration:", e.Env)
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p.Error())
+	}
+	return b.String()
+}
+
+// Is reports whether the target is ErrUnsafeConfig.
+func (e *UnsafeConfigError) Is(target error) bool {
+	return target == ErrUnsafeConfig
+}
+
+// Unwrap returns the list of found problems.
+func (e *UnsafeConfigError) Unwrap() []error {
+	return e.Problems
+}
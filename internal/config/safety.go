@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+
+	"braces.dev/errtrace"
+)
+
+// Known default or example secrets which must never be used outside of local development.
+var knownDefaultSecrets = []string{
+	"development-only-csrf-key-32byte",
+	"32-byte-long-auth-key",
+	"secret",
+	"changeme",
+	"change-me",
+}
+
+// isProductionLike reports whether the environment must be protected from insecure settings.
+func isProductionLike(env string) bool {
+	return env == EnvProduction || env == EnvStaging
+}
+
+// CheckSafety verifies that the configuration does not contain insecure defaults.
+// The check is performed only for production and staging environments,
+// for other environments it always returns nil.
+// The returned error is a human-readable report of every found problem.
+func (c Config) CheckSafety() error {
+	if !isProductionLike(c.App.Env) {
+		return nil
+	}
+
+	var problems []error
+	if c.App.DebugMode {
+		problems = append(problems, fieldErr("APP_DEBUG_MODE", "must be disabled, debug mode exposes the /debug profiler endpoints"))
+	}
+	for _, secret := range knownDefaultSecrets {
+		if strings.EqualFold(string(c.CSRF.Secret), secret) {
+			problems = append(problems, fieldErr("CSRF_SECRET", "uses a known default value, generate a random 32-byte secret"))
+			break
+		}
+	}
+	if c.CORS.AllowCredentials {
+		for _, origin := range c.CORS.AllowedOrigins {
+			if origin == "*" {
+				problems = append(problems, fieldErr("CORS_ALLOWED_ORIGINS", "wildcard origin \"*\" must not be combined with CORS_ALLOWED_CREDENTIALS=true, list the trusted origins explicitly"))
+				break
+			}
+		}
+	}
+	if !c.Session.CookieSecure {
+		problems = append(problems, fieldErr("SESSION_COOKIE_SECURE", "must be enabled, session and CSRF cookies would be sent over plain HTTP"))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return errtrace.Wrap(&UnsafeConfigError{Env: c.App.Env, Problems: problems})
+}
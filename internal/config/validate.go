@@ -0,0 +1,313 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dmitrymomot/go-app-template/db"
+)
+
+// Minimal length of the CSRF secret in bytes, gorilla/csrf requires a 32-byte auth key.
+const minCSRFSecretLength = 32
+
+// Validate checks every section of the configuration.
+// It returns all found problems joined into a single error, or nil.
+func (c Config) Validate() error {
+	return errors.Join(
+		c.App.Validate(),
+		c.DB.Validate(),
+		c.Migrations.Validate(),
+		c.HTTP.Validate(),
+		c.CORS.Validate(),
+		c.CSRF.Validate(),
+		c.Static.Validate(),
+		c.Redis.Validate(),
+		c.QueryCache.Validate(),
+		c.Outbox.Validate(),
+		c.Session.Validate(),
+		c.Postmark.Validate(),
+		c.Email.Validate(),
+		c.validateAutoMigrate(),
+	)
+}
+
+// validateAutoMigrate checks the database pool can run the migrations on startup:
+// the Postgres migrations lock holds a connection while the migrations need another one.
+func (c Config) validateAutoMigrate() error {
+	if !c.Migrations.AutoMigrate || c.DB.ReplicaPath != "" || c.DB.MaxOpenConns != 1 {
+		return nil
+	}
+	if dialect, err := db.DialectFromURL(c.DB.URL); err == nil && dialect == db.DialectPostgres {
+		return fieldErr("DATABASE_MAX_OPEN_CONNS", "must be at least 2 with AUTO_MIGRATE=true on Postgres, the migrations lock holds a connection")
+	}
+	return nil
+}
+
+// Validate checks the application settings.
+func (a App) Validate() error {
+	var errs []error
+	if a.Name == "" {
+		errs = append(errs, fieldErr("APP_NAME", "is required"))
+	}
+	switch a.Env {
+	case EnvLocal, EnvDevelopment, EnvStaging, EnvProduction, EnvTesting:
+	default:
+		errs = append(errs, fieldErr("APP_ENV", "must be one of %s, got %q",
+			strings.Join([]string{EnvLocal, EnvDevelopment, EnvStaging, EnvProduction, EnvTesting}, ", "), a.Env))
+	}
+	switch a.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fieldErr("APP_LOG_LEVEL", "must be one of debug, info, warn, error, got %q", a.LogLevel))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the database connection settings.
+func (d DB) Validate() error {
+	var errs []error
+	if d.URL == "" {
+		errs = append(errs, fieldErr("DATABASE_URL", "is required"))
+	} else if _, err := db.DialectFromURL(d.URL); err != nil {
+		errs = append(errs, fieldErr("DATABASE_URL", "must be a postgres://, libsql://, http(s)://, file: or :memory: URL"))
+	}
+	if d.ReplicaPath != "" {
+		if u, err := url.Parse(d.URL); err != nil || !hasScheme(u, "libsql", "http", "https") {
+			errs = append(errs, fieldErr("DATABASE_REPLICA_PATH", "embedded replica requires a remote libsql://, http(s):// DATABASE_URL"))
+		}
+	}
+	for i, u := range d.ReadReplicaURLs {
+		dialect, err := db.DialectFromURL(u)
+		if err != nil {
+			errs = append(errs, fieldErr("DATABASE_READ_REPLICA_URLS", "replica #%d must be a postgres://, libsql://, http(s)://, file: or :memory: URL", i+1))
+		} else if primary, err := db.DialectFromURL(d.URL); err == nil && dialect != primary {
+			errs = append(errs, fieldErr("DATABASE_READ_REPLICA_URLS", "replica #%d must have the same dialect as DATABASE_URL", i+1))
+		}
+	}
+	if d.SlowQueryThreshold < 0 {
+		errs = append(errs, fieldErr("DATABASE_SLOW_QUERY_THRESHOLD", "must not be negative, got %v", d.SlowQueryThreshold))
+	}
+	if d.NPlusOneThreshold < 0 {
+		errs = append(errs, fieldErr("DATABASE_NPLUSONE_THRESHOLD", "must not be negative, got %d", d.NPlusOneThreshold))
+	}
+	if d.SyncInterval < 0 {
+		errs = append(errs, fieldErr("DATABASE_SYNC_INTERVAL", "must not be negative, got %v", d.SyncInterval))
+	}
+	if d.ConnMaxLifetime < 0 {
+		errs = append(errs, fieldErr("DATABASE_CONN_MAX_LIFETIME", "must not be negative, got %v", d.ConnMaxLifetime))
+	}
+	if d.ConnMaxIdleTime < 0 {
+		errs = append(errs, fieldErr("DATABASE_CONN_MAX_IDLE_TIME", "must not be negative, got %v", d.ConnMaxIdleTime))
+	}
+	if d.ConnectTimeout < 0 {
+		errs = append(errs, fieldErr("DATABASE_CONNECT_TIMEOUT", "must not be negative, got %v", d.ConnectTimeout))
+	}
+	if d.MaxOpenConns < 1 {
+		errs = append(errs, fieldErr("DATABASE_MAX_OPEN_CONNS", "must be greater than 0, got %d", d.MaxOpenConns))
+	}
+	if d.MaxIdleConns < 0 {
+		errs = append(errs, fieldErr("DATABASE_IDLE_CONNS", "must not be negative, got %d", d.MaxIdleConns))
+	} else if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		errs = append(errs, fieldErr("DATABASE_IDLE_CONNS", "must not exceed DATABASE_MAX_OPEN_CONNS (%d), got %d", d.MaxOpenConns, d.MaxIdleConns))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the migrations settings.
+func (m Migrations) Validate() error {
+	var errs []error
+	if m.Table == "" {
+		errs = append(errs, fieldErr("DATABASE_MIGRATIONS_TABLE", "is required"))
+	}
+	errs = append(errs, positiveDuration("DATABASE_MIGRATIONS_LOCK_TIMEOUT", m.LockTimeout))
+	return errors.Join(errs...)
+}
+
+// Validate checks the HTTP server settings.
+func (h HTTP) Validate() error {
+	var errs []error
+	if h.Port < 1 || h.Port > 65535 {
+		errs = append(errs, fieldErr("HTTP_PORT", "must be between 1 and 65535, got %d", h.Port))
+	}
+	if h.MetricsPort < 0 || h.MetricsPort > 65535 {
+		errs = append(errs, fieldErr("HTTP_METRICS_PORT", "must be between 0 and 65535, got %d", h.MetricsPort))
+	} else if h.MetricsPort == h.Port {
+		errs = append(errs, fieldErr("HTTP_METRICS_PORT", "must differ from HTTP_PORT, the metrics must not be public"))
+	}
+	if h.ThrottleLimit < 1 {
+		errs = append(errs, fieldErr("HTTP_TROTTLE_LIMIT", "must be greater than 0, got %d", h.ThrottleLimit))
+	}
+	if h.ThrottleBacklog < 0 {
+		errs = append(errs, fieldErr("HTTP_TROTTLE_BACKLOG", "must not be negative, got %d", h.ThrottleBacklog))
+	}
+	if h.RequestLimit < 1 {
+		errs = append(errs, fieldErr("HTTP_REQUEST_LIMIT", "must be greater than 0, got %d", h.RequestLimit))
+	}
+	if h.BodyLimit < 1 {
+		errs = append(errs, fieldErr("HTTP_BODY_LIMIT", "must be greater than 0, got %d", h.BodyLimit))
+	}
+	errs = append(errs,
+		positiveDuration("HTTP_REQUEST_TIMEOUT", h.RequestTimeout),
+		positiveDuration("HTTP_TROTTLE_TIMEOUT", h.ThrottleTimeout),
+		positiveDuration("HTTP_RATE_LIMIT_WINDOW", h.RateLimitWindow),
+		positiveDuration("HTTP_READ_TIMEOUT", h.ReadTimeout),
+		positiveDuration("HTTP_WRITE_TIMEOUT", h.WriteTimeout),
+	)
+	return errors.Join(errs...)
+}
+
+// Validate checks the CORS settings.
+func (c CORS) Validate() error {
+	var errs []error
+	if len(c.AllowedOrigins) == 0 {
+		errs = append(errs, fieldErr("CORS_ALLOWED_ORIGINS", "must contain at least one origin"))
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "*.", "", 1)) // allow subdomain wildcards
+		if err != nil || !hasScheme(u, "http", "https") || u.Host == "" {
+			errs = append(errs, fieldErr("CORS_ALLOWED_ORIGINS", "%q must be \"*\" or an http(s) origin", origin))
+		}
+	}
+	for _, method := range c.AllowedMethods {
+		switch strings.ToUpper(method) {
+		case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+			http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		default:
+			errs = append(errs, fieldErr("CORS_ALLOWED_METHODS", "unknown HTTP method %q", method))
+		}
+	}
+	if c.MaxAge < 0 {
+		errs = append(errs, fieldErr("CORS_MAX_AGE", "must not be negative, got %d", c.MaxAge))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the CSRF protection settings.
+func (c CSRF) Validate() error {
+	if len(c.Secret) < minCSRFSecretLength {
+		return fieldErr("CSRF_SECRET", "must be at least %d bytes long, got %d", minCSRFSecretLength, len(c.Secret))
+	}
+	return nil
+}
+
+// Validate checks the static file serving settings.
+func (s Static) Validate() error {
+	var errs []error
+	if s.Dir == "" {
+		errs = append(errs, fieldErr("STATIC_DIR", "is required"))
+	} else if filepath.IsAbs(s.Dir) {
+		errs = append(errs, fieldErr("STATIC_DIR", "must be a relative path, got %q", s.Dir))
+	}
+	if !strings.HasPrefix(s.URLPrefix, "/") || strings.ContainsAny(s.URLPrefix, "{}") {
+		errs = append(errs, fieldErr("STATIC_URL_PREFIX", "must start with a slash and contain no URL parameters, got %q", s.URLPrefix))
+	}
+	if s.CacheTTL < 0 {
+		errs = append(errs, fieldErr("STATIC_CACHE_TTL", "must not be negative, got %v", s.CacheTTL))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the redis connection settings.
+func (r Redis) Validate() error {
+	if r.URL == "" {
+		return fieldErr("REDIS_URL", "is required")
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return fieldErr("REDIS_URL", "must be a valid URL")
+	}
+	if !hasScheme(u, "redis", "rediss", "unix") {
+		return fieldErr("REDIS_URL", "unsupported scheme %q, expected redis, rediss or unix", u.Scheme)
+	}
+	return nil
+}
+
+// Validate checks the query cache settings.
+func (q QueryCache) Validate() error {
+	if !q.Enabled {
+		return nil
+	}
+	var errs []error
+	if q.Prefix == "" {
+		errs = append(errs, fieldErr("QUERY_CACHE_PREFIX", "is required"))
+	}
+	if q.LocalSize < 0 {
+		errs = append(errs, fieldErr("QUERY_CACHE_LOCAL_SIZE", "must not be negative, got %d", q.LocalSize))
+	}
+	errs = append(errs, positiveDuration("QUERY_CACHE_LOCAL_TTL", q.LocalTTL))
+	return errors.Join(errs...)
+}
+
+// Validate checks the outbox relay settings.
+func (o Outbox) Validate() error {
+	var errs []error
+	if o.BatchSize < 1 {
+		errs = append(errs, fieldErr("OUTBOX_BATCH_SIZE", "must be greater than 0, got %d", o.BatchSize))
+	}
+	errs = append(errs,
+		positiveDuration("OUTBOX_POLL_INTERVAL", o.PollInterval),
+		positiveDuration("OUTBOX_MAX_BACKOFF", o.MaxBackoff),
+		positiveDuration("OUTBOX_RETENTION", o.Retention),
+	)
+	return errors.Join(errs...)
+}
+
+// Validate checks the session cookie settings.
+func (s Session) Validate() error {
+	var errs []error
+	if s.CookieName == "" {
+		errs = append(errs, fieldErr("SESSION_COOKIE_NAME", "is required"))
+	}
+	errs = append(errs, positiveDuration("SESSION_TTL", s.TTL))
+	return errors.Join(errs...)
+}
+
+// Validate checks the Postmark API credentials.
+func (p Postmark) Validate() error {
+	var errs []error
+	if p.ServerToken == "" {
+		errs = append(errs, fieldErr("POSTMARK_SERVER_TOKEN", "is required"))
+	}
+	if p.AccountToken == "" {
+		errs = append(errs, fieldErr("POSTMARK_ACCOUNT_TOKEN", "is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the outgoing email settings.
+func (e Email) Validate() error {
+	var errs []error
+	if _, err := mail.ParseAddress(e.From); err != nil {
+		errs = append(errs, fieldErr("EMAIL_FROM", "must be a valid email address, got %q", e.From))
+	}
+	if _, err := mail.ParseAddress(e.ReplyTo); err != nil {
+		errs = append(errs, fieldErr("EMAIL_REPLY_TO", "must be a valid email address, got %q", e.ReplyTo))
+	}
+	return errors.Join(errs...)
+}
+
+// positiveDuration returns an error if the duration is not positive.
+func positiveDuration(key string, d time.Duration) error {
+	if d <= 0 {
+		return fieldErr(key, "must be a positive duration, got %v", d)
+	}
+	return nil
+}
+
+// hasScheme reports whether the URL has one of the given schemes.
+func hasScheme(u *url.URL, schemes ...string) bool {
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+// Package binding decodes the HTTP requests into tagged structs and validates them.
+//
+// JSON bodies are decoded with encoding/json, so the fields are named by the json tags.
+// URL-encoded and multipart forms use the form tags, query parameters use the query tags,
+// both default to the field name. Nested structs are addressed with dots, e.g. "address.city".
+// Uploaded files are bound to *multipart.FileHeader and []*multipart.FileHeader fields.
+//
+//	type CreateAuthorInput struct {
+//		Name  string `json:"name" form:"name" validate:"required,max=100"`
+//		Email string `json:"email" form:"email" validate:"required,email"`
+//		Bio   string `json:"bio" form:"bio" validate:"max=1000"`
+//	}
+//
+//	var in CreateAuthorInput
+//	if err := binder.Bind(r, &in); err != nil {
+//		httperr.Render(w, r, err) // 400, 413, 415 or 422 with the field errors
+//		return
+//	}
+//
+// HTML forms are usually rendered again after a redirect instead, see Binder.Flash.
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+
+	"braces.dev/errtrace"
+	"github.com/alexedwards/scs/v2"
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+)
+
+// Default binder settings.
+const (
+	DefaultBodyLimit = 4 << 20 // 4MB
+	DefaultMaxMemory = 1 << 20 // 1MB
+)
+
+// Struct tags used by the binder.
+const (
+	TagJSON     = "json"
+	TagForm     = "form"
+	TagQuery    = "query"
+	TagValidate = "validate"
+)
+
+// Options defines the binder settings.
+type Options struct {
+	// BodyLimit is the maximum size of the request body in bytes. Default: 4MB.
+	BodyLimit int64
+	// MaxMemory is the maximum size of the multipart form kept in memory,
+	// the rest of the files is stored on disk. Default: 1MB.
+	MaxMemory int64
+	// Session stores the flashed forms, see Flash. It's required for Flash and Form only.
+	Session *scs.SessionManager
+	// SensitiveFields are never flashed. Default: the fields containing "password" and "_csrf".
+	SensitiveFields func(field string) bool
+}
+
+// Binder decodes and validates the requests.
+type Binder struct {
+	opts Options
+}
+
+// New creates a new binder.
+func New(opts Options) *Binder {
+	if opts.BodyLimit <= 0 {
+		opts.BodyLimit = DefaultBodyLimit
+	}
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = DefaultMaxMemory
+	}
+	if opts.SensitiveFields == nil {
+		opts.SensitiveFields = isSensitiveField
+	}
+	return &Binder{opts: opts}
+}
+
+// Bind decodes the request into the struct pointed by v and validates it.
+// GET, HEAD and DELETE requests are decoded from the query, the other ones from the body
+// by its content type: JSON, URL-encoded or multipart form.
+// Request errors are returned as *httperr.Error: ErrBadRequest for a malformed body,
+// ErrRequestTooLarge, ErrUnsupportedMediaType, and ErrValidation with the field errors.
+func (b *Binder) Bind(r *http.Request, v any) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return errtrace.Wrap(b.BindQuery(r, v))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		return errtrace.Wrap(b.BindJSON(r, v))
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		return errtrace.Wrap(b.BindForm(r, v))
+	}
+	return errtrace.Wrap(httperr.ErrUnsupportedMediaType.WithMessage(
+		"The request content type must be application/json, application/x-www-form-urlencoded or multipart/form-data.",
+	))
+}
+
+// BindQuery decodes the query parameters into the struct pointed by v and validates it.
+func (b *Binder) BindQuery(r *http.Request, v any) error {
+	fieldErrs, err := decodeValues(r.URL.Query(), nil, v, TagQuery)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	return errtrace.Wrap(validate(v, TagQuery, fieldErrs))
+}
+
+// BindJSON decodes the JSON body into the struct pointed by v and validates it.
+// The body must contain a single JSON value, trailing data is rejected with ErrBadRequest.
+func (b *Binder) BindJSON(r *http.Request, v any) error {
+	if err := checkTarget(v); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, b.opts.BodyLimit))
+	if err := dec.Decode(v); err != nil {
+		var (
+			maxBytesErr *http.MaxBytesError
+			typeErr     *json.UnmarshalTypeError
+		)
+		switch {
+		case errors.As(err, &maxBytesErr):
+			return errtrace.Wrap(tooLarge(b.opts.BodyLimit, err))
+		case errors.Is(err, io.EOF):
+			return errtrace.Wrap(httperr.ErrBadRequest.WithMessage("The request body is empty.").WithCause(err))
+		case errors.As(err, &typeErr):
+			// encoding/json reports only the first type mismatch
+			return errtrace.Wrap(validate(v, TagJSON, []httperr.FieldError{typeError(typeErr.Field, typeErr.Type)}))
+		}
+		return errtrace.Wrap(httperr.ErrBadRequest.WithMessage("The request body is not valid JSON.").WithCause(err))
+	}
+
+	// The body must be a single JSON value, anything but whitespace after it is rejected
+	if err := dec.Decode(&json.RawMessage{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return errtrace.Wrap(tooLarge(b.opts.BodyLimit, err))
+		}
+		return errtrace.Wrap(httperr.ErrBadRequest.WithMessage("The request body must contain a single JSON value.").WithCause(err))
+	}
+
+	return errtrace.Wrap(validate(v, TagJSON, nil))
+}
+
+// BindForm decodes the URL-encoded or multipart form into the struct pointed by v and validates it.
+// Only the body is decoded, use BindQuery for the query parameters.
+func (b *Binder) BindForm(r *http.Request, v any) error {
+	if err := checkTarget(v); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	r.Body = http.MaxBytesReader(nil, r.Body, b.opts.BodyLimit)
+	values, files, err := b.parseForm(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) || errors.Is(err, multipart.ErrMessageTooLarge) {
+			return errtrace.Wrap(tooLarge(b.opts.BodyLimit, err))
+		}
+		return errtrace.Wrap(httperr.ErrBadRequest.WithMessage("The form data is malformed.").WithCause(err))
+	}
+
+	fieldErrs, err := decodeValues(values, files, v, TagForm)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	return errtrace.Wrap(validate(v, TagForm, fieldErrs))
+}
+
+// parseForm parses the body of the request as a URL-encoded or multipart form.
+func (b *Binder) parseForm(r *http.Request) (map[string][]string, map[string][]*multipart.FileHeader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(b.opts.MaxMemory); err != nil {
+			return nil, nil, errtrace.Wrap(errors.Join(ErrFailedToParseMultipart, err))
+		}
+		return r.MultipartForm.Value, r.MultipartForm.File, nil
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, nil, errtrace.Wrap(err)
+	}
+	return r.PostForm, nil, nil
+}
+
+// tooLarge returns the error of the request body exceeding the limit.
+func tooLarge(limit int64, err error) error {
+	return httperr.ErrRequestTooLarge.
+		WithMessage(fmt.Sprintf("The request body must not exceed %d bytes.", limit)).
+		WithCause(err)
+}
@@ -0,0 +1,145 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+)
+
+type jsonAddress struct {
+	City string `json:"city" validate:"required"`
+	Zip  int    `json:"zip"`
+}
+
+type jsonInput struct {
+	Name    string      `json:"name" validate:"required,max=10"`
+	Age     int         `json:"age" validate:"required"`
+	Address jsonAddress `json:"address"`
+}
+
+// fieldNames returns the names of the field errors of the error.
+func fieldNames(err error) []string {
+	var e *httperr.Error
+	if !errors.As(err, &e) {
+		return nil
+	}
+	var names []string
+	for _, fe := range e.Fields {
+		names = append(names, fe.Field+":"+fe.Code)
+	}
+	return names
+}
+
+func TestBindJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantErr    error
+		wantFields []string
+	}{
+		{name: "valid", body: `{"name": "ok", "age": 30, "address": {"city": "Oslo"}}`},
+		{name: "trailing whitespace", body: "{\"name\": \"ok\", \"age\": 30, \"address\": {\"city\": \"Oslo\"}}\n\t "},
+		{name: "empty body", body: "", wantErr: httperr.ErrBadRequest},
+		{name: "invalid json", body: `{"name": `, wantErr: httperr.ErrBadRequest},
+		{name: "trailing data", body: `{"name": "ok", "age": 30, "address": {"city": "Oslo"}} trailing`, wantErr: httperr.ErrBadRequest},
+		{name: "trailing brace", body: `{"name": "ok", "age": 30, "address": {"city": "Oslo"}}}`, wantErr: httperr.ErrBadRequest},
+		{name: "second value", body: `{"name": "ok", "age": 30, "address": {"city": "Oslo"}} {}`, wantErr: httperr.ErrBadRequest},
+		{name: "too large", body: `{"name": "` + strings.Repeat("x", 1024) + `"}`, wantErr: httperr.ErrRequestTooLarge},
+		{
+			name:       "rules",
+			body:       `{"name": "too long name", "address": {}}`,
+			wantErr:    httperr.ErrValidation,
+			wantFields: []string{"name:max", "age:required", "address.city:required"},
+		},
+		{
+			// The field with the type error is zero, it's not reported as required too
+			name:       "type error",
+			body:       `{"name": "ok", "age": "thirty", "address": {"city": "Oslo"}}`,
+			wantErr:    httperr.ErrValidation,
+			wantFields: []string{"age:type"},
+		},
+		{
+			name:       "nested type error",
+			body:       `{"name": "ok", "age": 30, "address": {"city": "Oslo", "zip": "x"}}`,
+			wantErr:    httperr.ErrValidation,
+			wantFields: []string{"address.zip:type"},
+		},
+	}
+
+	b := New(Options{BodyLimit: 512})
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json; charset=utf-8")
+			var in jsonInput
+			err := b.Bind(r, &in)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Bind() error = %v", err)
+				}
+				if in.Name != "ok" || in.Age != 30 || in.Address.City != "Oslo" {
+					t.Errorf("Bind() decoded %+v", in)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Bind() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := fieldNames(err); !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("Bind() fields = %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestBindContentType(t *testing.T) {
+	t.Parallel()
+
+	type input struct {
+		Name string `json:"name" form:"name" query:"name"`
+	}
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		want        string
+		wantErr     error
+	}{
+		{name: "query", method: http.MethodGet, target: "/?name=query", want: "query"},
+		{name: "json", method: http.MethodPost, target: "/?name=query", contentType: "application/json", body: `{"name": "json"}`, want: "json"},
+		{name: "form", method: http.MethodPost, target: "/?name=query", contentType: "application/x-www-form-urlencoded", body: "name=form", want: "form"},
+		{name: "unsupported", method: http.MethodPost, target: "/", contentType: "text/plain", body: "name", wantErr: httperr.ErrUnsupportedMediaType},
+	}
+
+	b := New(Options{})
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			var in input
+			if err := b.Bind(r, &in); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Bind() error = %v, want %v", err, tt.wantErr)
+			}
+			if in.Name != tt.want {
+				t.Errorf("Bind() name = %q, want %q", in.Name, tt.want)
+			}
+		})
+	}
+}
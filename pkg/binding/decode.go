@@ -0,0 +1,239 @@
+package binding
+
+import (
+	"encoding"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+)
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+	timeType        = reflect.TypeOf(time.Time{})
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Time layouts accepted for time.Time fields, the ones of the HTML date and time inputs included.
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// checkTarget returns an error if v is not a non-nil pointer to a struct.
+func checkTarget(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errtrace.Wrap(ErrInvalidTarget)
+	}
+	return nil
+}
+
+// decodeValues decodes the form or query values and the files into the struct pointed by v.
+// The fields are named by the tag. Values which can't be converted to the field type
+// are returned as field errors, the field is left as is.
+func decodeValues(values map[string][]string, files map[string][]*multipart.FileHeader, v any, tag string) ([]httperr.FieldError, error) {
+	if err := checkTarget(v); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	var fieldErrs []httperr.FieldError
+	decodeStruct(reflect.ValueOf(v).Elem(), values, files, tag, "", &fieldErrs)
+	return fieldErrs, nil
+}
+
+// decodeStruct decodes the values into the fields of the struct, the names are prefixed with prefix.
+func decodeStruct(rv reflect.Value, values map[string][]string, files map[string][]*multipart.FileHeader, tag, prefix string, fieldErrs *[]httperr.FieldError) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !isBound(sf) {
+			continue
+		}
+		name, ok := fieldName(sf, tag)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		key := prefix + name
+
+		switch {
+		case sf.Type == fileHeaderType:
+			if fh := files[key]; len(fh) > 0 {
+				fv.Set(reflect.ValueOf(fh[0]))
+			}
+		case sf.Type == fileHeadersType:
+			if fh, ok := files[key]; ok {
+				fv.Set(reflect.ValueOf(fh))
+			}
+		case isNestedStruct(sf.Type):
+			// Embedded structs without a tag share the names of the parent
+			nestedPrefix := key + "."
+			if sf.Anonymous && sf.Tag.Get(tag) == "" {
+				nestedPrefix = prefix
+			}
+			decodeStruct(fv, values, files, tag, nestedPrefix, fieldErrs)
+		default:
+			vals, ok := values[key]
+			if !ok {
+				continue
+			}
+			if err := setValues(fv, vals); err != nil {
+				*fieldErrs = append(*fieldErrs, typeError(key, fv.Type()))
+			}
+		}
+	}
+}
+
+// isBound reports whether the field is decoded and validated: exported fields
+// and, as in encoding/json, embedded structs of unexported types, as their exported fields are promoted.
+func isBound(sf reflect.StructField) bool {
+	return sf.IsExported() || (sf.Anonymous && sf.Type.Kind() == reflect.Struct)
+}
+
+// fieldName returns the name of the field by the tag or the field name if there's no tag.
+// It returns false if the field is skipped with "-".
+func fieldName(sf reflect.StructField, tag string) (string, bool) {
+	name, _, _ := strings.Cut(sf.Tag.Get(tag), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return sf.Name, true
+	}
+	return name, true
+}
+
+// isNestedStruct reports whether the type is a struct decoded field by field.
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(textUnmarshaler)
+}
+
+// setValues sets the field to the values: all of them for a slice, the last one otherwise,
+// so a checkbox overrides the hidden input with the same name placed before it.
+func setValues(fv reflect.Value, vals []string) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setValue(slice.Index(i), s); err != nil {
+				return errtrace.Wrap(err)
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+	return errtrace.Wrap(setValue(fv, vals[len(vals)-1]))
+}
+
+// setValue converts the string to the type of the field and sets it.
+// An empty string leaves non-string fields zero, so the required rule reports them.
+func setValue(fv reflect.Value, s string) error {
+	if fv.Kind() == reflect.Pointer {
+		if s == "" {
+			return nil
+		}
+		v := reflect.New(fv.Type().Elem())
+		if err := setValue(v.Elem(), s); err != nil {
+			return errtrace.Wrap(err)
+		}
+		fv.Set(v)
+		return nil
+	}
+
+	if fv.Type() == timeType {
+		if s == "" {
+			return nil
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				fv.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return errtrace.Wrap(&time.ParseError{Value: s, Layout: time.RFC3339})
+	}
+
+	if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return errtrace.Wrap(u.UnmarshalText([]byte(s)))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+		return nil
+	case reflect.Slice: // []byte
+		fv.SetBytes([]byte(s))
+		return nil
+	}
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return errtrace.Wrap(err)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return errtrace.Wrap(err)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return errtrace.Wrap(err)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return errtrace.Wrap(err)
+		}
+		fv.SetFloat(n)
+	default:
+		return errtrace.Wrap(ErrInvalidTarget)
+	}
+	return nil
+}
+
+// parseBool parses the boolean values, "on" is sent by the checkboxes without a value.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+	return errtrace.Wrap2(strconv.ParseBool(s))
+}
+
+// typeError returns the field error of a value which can't be converted to the type.
+func typeError(field string, t reflect.Type) httperr.FieldError {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	msg := "Has an invalid format."
+	switch {
+	case t == timeType:
+		msg = "Must be a valid date."
+	case t.Kind() == reflect.Bool:
+		msg = "Must be true or false."
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64,
+		t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		msg = "Must be a whole number."
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		msg = "Must be a number."
+	case t.Kind() == reflect.String:
+		msg = "Must be a string."
+	}
+	return httperr.FieldError{Field: field, Code: "type", Message: msg}
+}
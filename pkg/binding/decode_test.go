@@ -0,0 +1,160 @@
+package binding
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+)
+
+type formMeta struct {
+	Source string `form:"source"`
+}
+
+type formAddress struct {
+	City string `form:"city" validate:"required"`
+	Zip  *int   `form:"zip"`
+}
+
+type formInput struct {
+	formMeta             // embedded without a tag, its fields share the names of the parent
+	Name     string      `form:"name" validate:"required"`
+	Age      int         `form:"age" validate:"required"`
+	Agree    bool        `form:"agree"`
+	Tags     []string    `form:"tags"`
+	IDs      []int64     `form:"ids"`
+	Born     time.Time   `form:"born"`
+	Address  formAddress `form:"address"`
+	Secret   string      `form:"-"`
+	NoTag    string
+}
+
+func TestDecodeValues(t *testing.T) {
+	t.Parallel()
+
+	zip := 1234
+	tests := []struct {
+		name       string
+		values     url.Values
+		want       formInput
+		wantFields []string
+	}{
+		{
+			name: "names",
+			values: url.Values{
+				"source":       {"ad"},
+				"name":         {"Rob"},
+				"age":          {" 42 "},
+				"agree":        {"off", "on"}, // the checkbox after the hidden input wins
+				"tags":         {"a", "b"},
+				"ids":          {"1", "2"},
+				"born":         {"2024-02-29"},
+				"address.city": {"Oslo"},
+				"address.zip":  {"1234"},
+				"Secret":       {"x"},
+				"-":            {"x"},
+				"NoTag":        {"field name"},
+			},
+			want: formInput{
+				formMeta: formMeta{Source: "ad"},
+				Name:     "Rob",
+				Age:      42,
+				Agree:    true,
+				Tags:     []string{"a", "b"},
+				IDs:      []int64{1, 2},
+				Born:     time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+				Address:  formAddress{City: "Oslo", Zip: &zip},
+				NoTag:    "field name",
+			},
+		},
+		{
+			name:   "empty values are zero",
+			values: url.Values{"name": {"Rob"}, "age": {"1"}, "address.city": {"Oslo"}, "address.zip": {""}, "born": {""}},
+			want:   formInput{Name: "Rob", Age: 1, Address: formAddress{City: "Oslo"}},
+		},
+		{
+			// A field with a type error is reported once, not as required too,
+			// and the slice is reported once for any number of invalid elements
+			name:       "type errors",
+			values:     url.Values{"name": {"Rob"}, "age": {"x"}, "agree": {"maybe"}, "ids": {"1", "x", "y"}, "born": {"yesterday"}, "address.city": {"Oslo"}, "address.zip": {"x"}},
+			want:       formInput{Name: "Rob", Address: formAddress{City: "Oslo"}},
+			wantFields: []string{"age:type", "agree:type", "ids:type", "born:type", "address.zip:type"},
+		},
+		{
+			name:       "required nested",
+			values:     url.Values{"name": {"Rob"}, "age": {"1"}},
+			want:       formInput{Name: "Rob", Age: 1},
+			wantFields: []string{"address.city:required"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got formInput
+			fieldErrs, err := decodeValues(tt.values, nil, &got, TagForm)
+			if err != nil {
+				t.Fatalf("decodeValues() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeValues() = %+v, want %+v", got, tt.want)
+			}
+
+			err = validate(&got, TagForm, fieldErrs)
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Errorf("validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, httperr.ErrValidation) {
+				t.Fatalf("validate() error = %v, want %v", err, httperr.ErrValidation)
+			}
+			if names := fieldNames(err); !reflect.DeepEqual(names, tt.wantFields) {
+				t.Errorf("validate() fields = %v, want %v", names, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestDecodeValuesTarget(t *testing.T) {
+	t.Parallel()
+
+	var s string
+	var nilInput *formInput
+	for _, v := range []any{nil, s, &s, formInput{}, nilInput} {
+		if _, err := decodeValues(url.Values{}, nil, v, TagForm); !errors.Is(err, ErrInvalidTarget) {
+			t.Errorf("decodeValues(%T) error = %v, want %v", v, err, ErrInvalidTarget)
+		}
+	}
+}
+
+func TestTypeError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{value: 0, want: "Must be a whole number."},
+		{value: uint8(0), want: "Must be a whole number."},
+		{value: []*int{}, want: "Must be a whole number."},
+		{value: 0.5, want: "Must be a number."},
+		{value: false, want: "Must be true or false."},
+		{value: time.Time{}, want: "Must be a valid date."},
+		{value: "", want: "Must be a string."},
+		{value: struct{}{}, want: "Has an invalid format."},
+	}
+
+	for _, tt := range tests {
+		fe := typeError("field", reflect.TypeOf(tt.value))
+		if fe.Message != tt.want || fe.Code != "type" || fe.Field != "field" {
+			t.Errorf("typeError(%T) = %+v, want the message %q", tt.value, fe, tt.want)
+		}
+	}
+}
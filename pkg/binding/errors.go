@@ -0,0 +1,13 @@
+package binding
+
+import "errors"
+
+// Predefined errors.
+// Request errors are returned as *httperr.Error, these ones are programming errors.
+var (
+	ErrInvalidTarget          = errors.New("binding target must be a non-nil pointer to a struct")
+	ErrUnknownRule            = errors.New("unknown validation rule")
+	ErrInvalidRule            = errors.New("invalid validation rule")
+	ErrMissedSession          = errors.New("missed session manager")
+	ErrFailedToParseMultipart = errors.New("failed to parse multipart form")
+)
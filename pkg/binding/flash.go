@@ -0,0 +1,121 @@
+package binding
+
+import (
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+)
+
+// Session keys of the flashed form.
+const (
+	flashInputKey   = "binding.input"
+	flashErrorsKey  = "binding.errors"
+	flashMessageKey = "binding.message"
+)
+
+func init() {
+	// The session values are encoded with gob
+	gob.Register(url.Values{})
+	gob.Register(map[string]string{})
+}
+
+// FormState is the submitted input and the errors of a form flashed before the redirect,
+// it's used to render the form again with the entered values, see Binder.Flash.
+type FormState struct {
+	Input   url.Values
+	Errors  map[string]string // Field errors by the field name
+	Message string            // Message of the error
+}
+
+// Value returns the submitted value of the field.
+func (f FormState) Value(field string) string {
+	return f.Input.Get(field)
+}
+
+// Error returns the error message of the field or an empty string.
+func (f FormState) Error(field string) string {
+	return f.Errors[field]
+}
+
+// HasErrors reports whether the form has failed.
+func (f FormState) HasErrors() bool {
+	return f.Message != "" || len(f.Errors) > 0
+}
+
+// Flash stores the submitted form and the error returned by Bind in the session until the next
+// request, so the form can be rendered again after the redirect (Post/Redirect/Get), see Form.
+// The values of the sensitive fields, e.g. passwords, are never stored.
+//
+//	if err := binder.Bind(r, &in); err != nil {
+//		if err := binder.Flash(r, err); err != nil {
+//			httperr.Render(w, r, err)
+//			return
+//		}
+//		http.Redirect(w, r, "/authors/new", http.StatusSeeOther)
+//		return
+//	}
+func (b *Binder) Flash(r *http.Request, err error) error {
+	if b.opts.Session == nil {
+		return errtrace.Wrap(ErrMissedSession)
+	}
+
+	var e *httperr.Error
+	if !errors.As(err, &e) || e.Unexpected() {
+		// Unexpected errors are rendered, not shown next to the form
+		return errtrace.Wrap(err)
+	}
+
+	input := url.Values{}
+	for field, vals := range formValues(r) {
+		if !b.opts.SensitiveFields(field) {
+			input[field] = vals
+		}
+	}
+	fieldErrs := make(map[string]string, len(e.Fields))
+	for _, fe := range e.Fields {
+		if _, ok := fieldErrs[fe.Field]; !ok {
+			fieldErrs[fe.Field] = fe.Message
+		}
+	}
+
+	ctx := r.Context()
+	b.opts.Session.Put(ctx, flashInputKey, input)
+	b.opts.Session.Put(ctx, flashErrorsKey, fieldErrs)
+	b.opts.Session.Put(ctx, flashMessageKey, e.Message)
+	return nil
+}
+
+// Form returns the form flashed by the previous request and removes it from the session.
+// It returns an empty state if there's none.
+func (b *Binder) Form(r *http.Request) FormState {
+	if b.opts.Session == nil {
+		return FormState{}
+	}
+	ctx := r.Context()
+	input, _ := b.opts.Session.Pop(ctx, flashInputKey).(url.Values)
+	fieldErrs, _ := b.opts.Session.Pop(ctx, flashErrorsKey).(map[string]string)
+	return FormState{
+		Input:   input,
+		Errors:  fieldErrs,
+		Message: b.opts.Session.PopString(ctx, flashMessageKey),
+	}
+}
+
+// formValues returns the parsed values of the submitted form.
+func formValues(r *http.Request) url.Values {
+	if r.MultipartForm != nil {
+		return r.MultipartForm.Value
+	}
+	return r.PostForm
+}
+
+// isSensitiveField reports whether the field must not be flashed.
+func isSensitiveField(field string) bool {
+	field = strings.ToLower(field)
+	return strings.Contains(field, "password") || field == "_csrf"
+}
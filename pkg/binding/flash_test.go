@@ -0,0 +1,151 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+)
+
+type signupInput struct {
+	Name     string `form:"name" validate:"required"`
+	Email    string `form:"email" validate:"required,email"`
+	Password string `form:"password" validate:"required,min=8"`
+}
+
+// Validate implements Validator, it reports the name again to check that Flash keeps the first error.
+func (s *signupInput) Validate() []httperr.FieldError {
+	if s.Name == "" {
+		return []httperr.FieldError{{Field: "name", Code: "taken", Message: "Is taken."}}
+	}
+	return nil
+}
+
+func TestFlash(t *testing.T) {
+	t.Parallel()
+
+	sm := scs.New()
+	b := New(Options{Session: sm})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/signup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var in signupInput
+			if err := b.Flash(r, b.Bind(r, &in)); err != nil {
+				t.Errorf("Flash() error = %v", err)
+			}
+			http.Redirect(w, r, "/signup", http.StatusSeeOther)
+			return
+		}
+		form := b.Form(r)
+		w.Header().Set("X-Name", form.Value("name"))
+		w.Header().Set("X-Email", form.Value("email"))
+		w.Header().Set("X-Password", form.Value("password"))
+		w.Header().Set("X-CSRF", form.Value("_csrf"))
+		w.Header().Set("X-Errors", strings.Join([]string{form.Error("name"), form.Error("email"), form.Error("password")}, "|"))
+		w.Header().Set("X-Message", form.Message)
+		if form.HasErrors() {
+			w.Header().Set("X-Has-Errors", "true")
+		}
+	})
+	h := sm.LoadAndSave(mux)
+
+	form := url.Values{"name": {""}, "email": {"rob.example.com"}, "password": {"secret"}, "_csrf": {"token"}}
+	r := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	cookies := w.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatalf("the session cookie is not set")
+	}
+
+	get := func() http.Header {
+		r := httptest.NewRequest(http.MethodGet, "/signup", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Header()
+	}
+
+	got := get()
+	want := map[string]string{
+		"X-Name":       "",
+		"X-Email":      "rob.example.com",
+		"X-Password":   "", // sensitive fields are never flashed
+		"X-Csrf":       "",
+		"X-Errors":     "This field is required.|Must be a valid email address.|Must be at least 8 characters long.",
+		"X-Message":    httperr.ErrValidation.Message,
+		"X-Has-Errors": "true",
+	}
+	for key, v := range want {
+		if got.Get(key) != v {
+			t.Errorf("%s = %q, want %q", key, got.Get(key), v)
+		}
+	}
+
+	// The form is shown once
+	if got := get(); got.Get("X-Has-Errors") != "" || got.Get("X-Email") != "" {
+		t.Errorf("the second request got the flashed form: %v", got)
+	}
+}
+
+func TestFlashErrors(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	if err := New(Options{}).Flash(r, httperr.ErrValidation); !errors.Is(err, ErrMissedSession) {
+		t.Errorf("Flash() without session error = %v, want %v", err, ErrMissedSession)
+	}
+
+	// Unexpected errors are returned to be rendered, the session isn't touched
+	b := New(Options{Session: scs.New()})
+	for _, err := range []error{errors.New("db is down"), httperr.ErrInternal} {
+		if got := b.Flash(r, err); !errors.Is(got, err) {
+			t.Errorf("Flash(%v) error = %v, want the error returned", err, got)
+		}
+	}
+}
+
+func TestIsSensitiveField(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"password":         true,
+		"confirm_Password": true,
+		"_csrf":            true,
+		"_CSRF":            true,
+		"email":            false,
+		"csrf_note":        false,
+	}
+	for field, want := range tests {
+		if got := isSensitiveField(field); got != want {
+			t.Errorf("isSensitiveField(%q) = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestFormState(t *testing.T) {
+	t.Parallel()
+
+	var empty FormState
+	if empty.HasErrors() || empty.Value("name") != "" || empty.Error("name") != "" {
+		t.Errorf("the zero FormState = %+v, want no values and errors", empty)
+	}
+
+	s := FormState{Input: url.Values{"name": {"a", "b"}}, Errors: map[string]string{"name": "Is taken."}}
+	if got := []string{s.Value("name"), s.Error("name")}; !reflect.DeepEqual(got, []string{"a", "Is taken."}) {
+		t.Errorf("FormState value and error = %v", got)
+	}
+	if !s.HasErrors() {
+		t.Errorf("HasErrors() = false, want true")
+	}
+}
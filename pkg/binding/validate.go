@@ -0,0 +1,247 @@
+package binding
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+)
+
+// Validator is implemented by the structs with checks beyond the declarative rules,
+// e.g. comparing fields. It's called after the rules.
+type Validator interface {
+	Validate() []httperr.FieldError
+}
+
+// Validate validates the struct pointed by v with the rules of its validate tags
+// and returns httperr.ErrValidation with the field errors, named by the json tags.
+//
+// Rules are separated by commas, all rules except required skip zero values:
+//   - required: the value is not zero, strings must contain a non-space character
+//   - min=N, max=N, len=N: the length of a string (in characters), slice or map, or the number value
+//   - email: a single email address without a display name
+//   - url: an absolute http(s) URL
+//   - oneof=a b c: one of the space-separated values
+//
+// Nested structs and slices of structs are validated too, e.g. "items[0].name".
+func Validate(v any) error {
+	return errtrace.Wrap(validate(v, TagJSON, nil))
+}
+
+// validate validates v and returns ErrValidation with the field errors, including
+// the given ones of the decoding, or nil if there are none. The fields are named by the tag.
+func validate(v any, tag string, fieldErrs []httperr.FieldError) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errtrace.Wrap(ErrInvalidTarget)
+	}
+
+	// Fields with decoding errors have zero values, don't report them twice
+	invalid := make(map[string]bool, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		invalid[fe.Field] = true
+	}
+	var ruleErrs []httperr.FieldError
+	if err := validateStruct(rv.Elem(), tag, "", &ruleErrs); err != nil {
+		return errtrace.Wrap(err)
+	}
+	for _, fe := range ruleErrs {
+		if !invalid[fe.Field] {
+			fieldErrs = append(fieldErrs, fe)
+		}
+	}
+
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+	return errtrace.Wrap(httperr.ErrValidation.WithFields(fieldErrs...))
+}
+
+// validateStruct applies the rules to the fields of the struct, the names are prefixed with prefix.
+func validateStruct(rv reflect.Value, tag, prefix string, fieldErrs *[]httperr.FieldError) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !isBound(sf) {
+			continue
+		}
+		name, ok := fieldName(sf, tag)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		key := prefix + name
+
+		if rules := sf.Tag.Get(TagValidate); rules != "" && rules != "-" {
+			fe, err := applyRules(key, fv, rules)
+			if err != nil {
+				return errtrace.Wrap(fmt.Errorf("%w: field %s.%s", err, rt.Name(), sf.Name))
+			}
+			if fe != nil {
+				*fieldErrs = append(*fieldErrs, *fe)
+				continue
+			}
+		}
+
+		// Nested structs, embedded ones share the names of the parent
+		switch {
+		case isNestedStruct(sf.Type):
+			nestedPrefix := key + "."
+			if sf.Anonymous && sf.Tag.Get(tag) == "" {
+				nestedPrefix = prefix
+			}
+			if err := validateStruct(fv, tag, nestedPrefix, fieldErrs); err != nil {
+				return errtrace.Wrap(err)
+			}
+		case sf.Type.Kind() == reflect.Pointer && isNestedStruct(sf.Type.Elem()) && !fv.IsNil():
+			if err := validateStruct(fv.Elem(), tag, key+".", fieldErrs); err != nil {
+				return errtrace.Wrap(err)
+			}
+		case sf.Type.Kind() == reflect.Slice && isNestedStruct(sf.Type.Elem()):
+			for j := 0; j < fv.Len(); j++ {
+				if err := validateStruct(fv.Index(j), tag, key+"["+strconv.Itoa(j)+"].", fieldErrs); err != nil {
+					return errtrace.Wrap(err)
+				}
+			}
+		}
+	}
+
+	// The methods of an embedded struct of an unexported type can't be called through reflection,
+	// they are promoted to the parent struct instead
+	if !rv.Addr().CanInterface() {
+		return nil
+	}
+	if fv, ok := rv.Addr().Interface().(Validator); ok {
+		for _, fe := range fv.Validate() {
+			fe.Field = prefix + fe.Field
+			*fieldErrs = append(*fieldErrs, fe)
+		}
+	}
+	return nil
+}
+
+// applyRules checks the value against the comma-separated rules and returns the error of the first failed one.
+func applyRules(field string, fv reflect.Value, rules string) (*httperr.FieldError, error) {
+	zero := isZero(fv)
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if name == "required" {
+			if zero {
+				return &httperr.FieldError{Field: field, Code: name, Message: "This field is required."}, nil
+			}
+			continue
+		}
+		if zero {
+			continue
+		}
+
+		msg, err := checkRule(fv, name, param)
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		if msg != "" {
+			return &httperr.FieldError{Field: field, Code: name, Message: msg}, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkRule returns the message of the failed rule or an empty string if the value is valid.
+func checkRule(fv reflect.Value, name, param string) (string, error) {
+	for fv.Kind() == reflect.Pointer {
+		fv = fv.Elem()
+	}
+
+	switch name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return "", errtrace.Wrap(fmt.Errorf("%w: %s=%s", ErrInvalidRule, name, param))
+		}
+		return errtrace.Wrap2(checkSize(fv, name, limit))
+
+	case "email":
+		s := fv.String()
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "Must be a valid email address.", nil
+		}
+
+	case "url":
+		u, err := url.ParseRequestURI(fv.String())
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "Must be a valid URL.", nil
+		}
+
+	case "oneof":
+		options := strings.Fields(param)
+		if !slices.Contains(options, fmt.Sprint(fv.Interface())) {
+			return "Must be one of: " + strings.Join(options, ", ") + ".", nil
+		}
+
+	default:
+		return "", errtrace.Wrap(fmt.Errorf("%w: %s", ErrUnknownRule, name))
+	}
+	return "", nil
+}
+
+// checkSize checks the length of a string, slice or map, or the number value against the limit.
+func checkSize(fv reflect.Value, rule string, limit float64) (string, error) {
+	var (
+		size float64
+		unit string
+	)
+	switch fv.Kind() {
+	case reflect.String:
+		size, unit = float64(utf8.RuneCountInString(fv.String())), " characters long"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		size, unit = float64(fv.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = fv.Float()
+	default:
+		return "", errtrace.Wrap(fmt.Errorf("%w: %s is not supported by %s", ErrInvalidRule, fv.Type(), rule))
+	}
+
+	n := strconv.FormatFloat(limit, 'f', -1, 64)
+	switch {
+	case rule == "min" && size < limit:
+		if unit == "" {
+			return "Must be at least " + n + ".", nil
+		}
+		return "Must be at least " + n + unit + ".", nil
+	case rule == "max" && size > limit:
+		if unit == "" {
+			return "Must be at most " + n + ".", nil
+		}
+		return "Must be at most " + n + unit + ".", nil
+	case rule == "len" && size != limit:
+		if unit == "" {
+			return "Must be equal to " + n + ".", nil
+		}
+		return "Must be exactly " + n + unit + ".", nil
+	}
+	return "", nil
+}
+
+// isZero reports whether the value is empty for the required rule.
+func isZero(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(fv.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return fv.IsNil()
+	}
+	return fv.IsZero()
+}
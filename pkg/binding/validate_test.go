@@ -0,0 +1,159 @@
+package binding
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+)
+
+type Audit struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type item struct {
+	Name string `json:"name" validate:"required"`
+	Qty  int    `json:"qty" validate:"min=1"`
+}
+
+type period struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Validate implements Validator.
+func (p *period) Validate() []httperr.FieldError {
+	if p.To < p.From {
+		return []httperr.FieldError{{Field: "to", Code: "range", Message: "Must not be before from."}}
+	}
+	return nil
+}
+
+type order struct {
+	Audit
+	Email    string  `json:"email" validate:"required,email"`
+	Website  string  `json:"website" validate:"url"`
+	Status   string  `json:"status" validate:"oneof=new paid"`
+	Code     string  `json:"code" validate:"len=3"`
+	Items    []item  `json:"items" validate:"required,max=2"`
+	Shipping *item   `json:"shipping"`
+	Period   period  `json:"period"`
+	Note     string  `json:"-" validate:"required"`
+	Score    float64 `json:"score" validate:"max=5"`
+}
+
+// validOrder returns the order passing all rules.
+func validOrder() order {
+	return order{
+		Audit:   Audit{Reason: "test"},
+		Email:   "rob@example.com",
+		Website: "https://example.com",
+		Status:  "paid",
+		Code:    "abc",
+		Items:   []item{{Name: "pen", Qty: 1}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		modify     func(o *order)
+		wantFields []string
+	}{
+		{name: "valid", modify: func(*order) {}},
+		{name: "embedded", modify: func(o *order) { o.Reason = " " }, wantFields: []string{"reason:required"}},
+		{name: "email", modify: func(o *order) { o.Email = "Rob <rob@example.com>" }, wantFields: []string{"email:email"}},
+		{name: "url", modify: func(o *order) { o.Website = "ftp://example.com" }, wantFields: []string{"website:url"}},
+		{name: "oneof", modify: func(o *order) { o.Status = "lost" }, wantFields: []string{"status:oneof"}},
+		{name: "len counts characters", modify: func(o *order) { o.Code = "äöü" }},
+		{name: "len", modify: func(o *order) { o.Code = "ab" }, wantFields: []string{"code:len"}},
+		{name: "number", modify: func(o *order) { o.Score = 5.5 }, wantFields: []string{"score:max"}},
+		{name: "required slice", modify: func(o *order) { o.Items = nil }, wantFields: []string{"items:required"}},
+		{
+			// The elements of the slice failing its own rule are not validated
+			name:       "slice rule",
+			modify:     func(o *order) { o.Items = []item{{}, {}, {}} },
+			wantFields: []string{"items:max"},
+		},
+		{
+			name:       "slice elements",
+			modify:     func(o *order) { o.Items = []item{{Name: "pen", Qty: 1}, {Qty: -1}} },
+			wantFields: []string{"items[1].name:required", "items[1].qty:min"},
+		},
+		{name: "nil pointer", modify: func(o *order) { o.Shipping = nil }},
+		{
+			name:       "pointer",
+			modify:     func(o *order) { o.Shipping = &item{Qty: 1} },
+			wantFields: []string{"shipping.name:required"},
+		},
+		{
+			name:       "validator",
+			modify:     func(o *order) { o.Period = period{From: 2, To: 1} },
+			wantFields: []string{"period.to:range"},
+		},
+		{
+			name: "order of fields",
+			modify: func(o *order) {
+				o.Reason, o.Email, o.Items = "", "", []item{{Qty: 1}}
+			},
+			wantFields: []string{"reason:required", "email:required", "items[0].name:required"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			o := validOrder()
+			tt.modify(&o)
+			err := Validate(&o)
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, httperr.ErrValidation) {
+				t.Fatalf("Validate() error = %v, want %v", err, httperr.ErrValidation)
+			}
+			if got := fieldNames(err); !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("Validate() fields = %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidRules(t *testing.T) {
+	t.Parallel()
+
+	unknown := struct {
+		Name string `validate:"alpha"`
+	}{Name: "x"}
+	invalid := struct {
+		Name string `validate:"max=x"`
+	}{Name: "x"}
+	unsupported := struct {
+		Flag bool `validate:"min=1"`
+	}{Flag: true}
+
+	tests := []struct {
+		name    string
+		v       any
+		wantErr error
+	}{
+		{name: "unknown", v: &unknown, wantErr: ErrUnknownRule},
+		{name: "invalid", v: &invalid, wantErr: ErrInvalidRule},
+		{name: "unsupported type", v: &unsupported, wantErr: ErrInvalidRule},
+		{name: "not a pointer", v: unknown, wantErr: ErrInvalidTarget},
+	}
+
+	for _, tt := range tests {
+		if err := Validate(tt.v); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Validate() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
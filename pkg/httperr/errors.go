@@ -0,0 +1,22 @@
+package httperr
+
+import "net/http"
+
+// Predefined errors.
+// Use their With* methods to set the message, the field details or the cause of a specific error,
+// errors.Is matches the result with the predefined error.
+var (
+	ErrBadRequest           = New(http.StatusBadRequest, "bad_request", "The request is invalid.")
+	ErrUnauthorized         = New(http.StatusUnauthorized, "unauthorized", "Authentication is required.")
+	ErrForbidden            = New(http.StatusForbidden, "forbidden", "You don't have permission to perform this action.")
+	ErrNotFound             = New(http.StatusNotFound, "not_found", "The requested resource was not found.")
+	ErrMethodNotAllowed     = New(http.StatusMethodNotAllowed, "method_not_allowed", "The method is not allowed for the requested resource.")
+	ErrConflict             = New(http.StatusConflict, "conflict", "The resource already exists.")
+	ErrRequestTooLarge      = New(http.StatusRequestEntityTooLarge, "request_too_large", "The request body is too large.")
+	ErrUnsupportedMediaType = New(http.StatusUnsupportedMediaType, "unsupported_media_type", "The request content type is not supported.")
+	ErrValidation           = New(http.StatusUnprocessableEntity, "validation_failed", "The request contains invalid fields.")
+	ErrTooManyRequests      = New(http.StatusTooManyRequests, "too_many_requests", "Too many requests, please try again later.")
+	ErrInternal             = New(http.StatusInternalServerError, "internal_error", "Something went wrong, please try again later.")
+	ErrServiceUnavailable   = New(http.StatusServiceUnavailable, "service_unavailable", "The service is temporarily unavailable, please try again later.")
+	ErrTimeout              = New(http.StatusGatewayTimeout, "timeout", "The request took too long to process.")
+)
@@ -0,0 +1,137 @@
+// Package httperr provides the HTTP error type shared by the handlers and its rendering.
+//
+// An Error carries everything needed to respond to the client: the status code,
+// a machine-readable code, a user-safe message and the field-level validation details.
+// The internal cause is kept for logging only and is never sent to the client.
+//
+//	author, err := repo.GetAuthor(ctx, id)
+//	if err != nil {
+//		httperr.Render(w, r, err) // database errors are mapped to 404, 409, 422 or 503
+//		return
+//	}
+//
+//	if name == "" {
+//		httperr.Render(w, r, httperr.ErrValidation.WithFields(httperr.FieldError{
+//			Field: "name", Code: "required", Message: "Name is required.",
+//		}))
+//		return
+//	}
+package httperr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dmitrymomot/go-app-template/db/dberr"
+)
+
+// Error is an HTTP error with a user-safe message.
+type Error struct {
+	Status  int          // HTTP status code
+	Code    string       // Machine-readable error code, e.g. "not_found"
+	Message string       // User-safe message
+	Fields  []FieldError // Field-level validation details
+	cause   error
+}
+
+// FieldError describes an invalid field of the request.
+type FieldError struct {
+	Field   string `json:"field"`   // Field name as sent by the client, e.g. "email" or "items[0].name"
+	Code    string `json:"code"`    // Machine-readable rule, e.g. "required"
+	Message string `json:"message"` // User-safe message
+}
+
+// New creates a new error. Invalid status codes are replaced with 500.
+func New(status int, code, message string) *Error {
+	if status < 400 || status > 599 {
+		status = http.StatusInternalServerError
+	}
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Error returns the code and the message or the cause of the error, it's meant for logs.
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Code + ": " + e.cause.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Unwrap returns the internal cause of the error.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the target is an *Error with the same status and code,
+// so a customized error matches the predefined one.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Status == e.Status && t.Code == e.Code
+}
+
+// Unexpected reports whether the error is a server-side failure which must be logged.
+func (e *Error) Unexpected() bool {
+	return e.Status >= http.StatusInternalServerError
+}
+
+// WithMessage returns a copy of the error with the user-safe message.
+func (e *Error) WithMessage(message string) *Error {
+	c := e.clone()
+	c.Message = message
+	return c
+}
+
+// WithFields returns a copy of the error with the field errors added.
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	c := e.clone()
+	c.Fields = append(c.Fields, fields...)
+	return c
+}
+
+// WithCause returns a copy of the error with the internal cause.
+// The cause is logged, the client gets only the message.
+func (e *Error) WithCause(err error) *Error {
+	c := e.clone()
+	c.cause = err
+	return c
+}
+
+// clone returns a copy of the error, so the predefined errors are never modified.
+func (e *Error) clone() *Error {
+	c := *e
+	c.Fields = slices.Clone(e.Fields)
+	return &c
+}
+
+// From converts any error to *Error.
+// An *Error in the chain is returned as is, database errors are mapped with dberr
+// and everything else becomes ErrInternal with err as the cause. It returns nil for nil.
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	// Database errors get their own status code and a message without driver details
+	if status := dberr.HTTPStatus(err); status != 0 {
+		return New(status, codeOf(status), dberr.Message(err)).WithCause(err)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout.WithCause(err)
+	}
+
+	return ErrInternal.WithCause(err)
+}
+
+// codeOf returns the machine-readable code of the status, e.g. "not_found" for 404.
+func codeOf(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
@@ -0,0 +1,39 @@
+package httperr
+
+import (
+	"net/http"
+
+	"github.com/dmitrymomot/go-app-template/pkg/negotiate"
+)
+
+// ContentTypeProblemJSON is the media type of the problem details, see RFC 7807.
+const ContentTypeProblemJSON = negotiate.MediaTypeProblemJSON
+
+// Problem is the problem details object of RFC 7807 with the error code
+// and the field errors as extension members.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Problem returns the problem details of the error for the request.
+// The type is "about:blank", so the title is the status text.
+func (e *Error) Problem(r *http.Request) Problem {
+	p := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(e.Status),
+		Status: e.Status,
+		Detail: e.Message,
+		Code:   e.Code,
+		Errors: e.Fields,
+	}
+	if r != nil && r.URL != nil {
+		p.Instance = r.URL.Path
+	}
+	return p
+}
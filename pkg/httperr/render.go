@@ -0,0 +1,156 @@
+package httperr
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"braces.dev/errtrace"
+	"github.com/a-h/templ"
+	"github.com/dmitrymomot/go-app-template/pkg/negotiate"
+	"go.uber.org/zap"
+)
+
+// PageFunc returns the HTML of the error, e.g. views.ErrorPage.
+type PageFunc func(status int, message string) templ.Component
+
+// DefaultToastTarget is the default CSS selector of the toast area.
+const DefaultToastTarget = "#toasts"
+
+// Options defines the renderer settings.
+type Options struct {
+	// Logger logs the unexpected errors with their trace. Default: the global zap logger.
+	Logger *zap.SugaredLogger
+	// Page renders the error for HTML clients. Default: plain text.
+	Page PageFunc
+	// Fragment renders the error for htmx requests, e.g. views.ErrorToast. Default: plain text.
+	Fragment PageFunc
+	// ToastTarget is the CSS selector of the element the fragment is appended to,
+	// set with the HX-Retarget header. Default: "#toasts".
+	ToastTarget string
+	// IsJSON reports whether the client expects JSON. Default: negotiate.PrefersJSON.
+	IsJSON func(r *http.Request) bool
+}
+
+// Renderer writes errors to the clients: problem+json for JSON clients, a fragment for htmx requests
+// and the HTML page for everyone else.
+type Renderer struct {
+	opts Options
+}
+
+// NewRenderer creates a new error renderer.
+func NewRenderer(opts Options) *Renderer {
+	if opts.IsJSON == nil {
+		opts.IsJSON = negotiate.PrefersJSON
+	}
+	if opts.ToastTarget == "" {
+		opts.ToastTarget = DefaultToastTarget
+	}
+	return &Renderer{opts: opts}
+}
+
+// Render writes the error response. Any error is converted with From,
+// so the client never sees the message of an unexpected error, it's logged instead.
+func (rr *Renderer) Render(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	if e == nil {
+		e = ErrInternal
+	}
+	rr.log(r, e)
+	rr.write(w, r, e)
+}
+
+// Write writes the error response like Render, but doesn't log the error,
+// e.g. if the caller has logged it with more details.
+func (rr *Renderer) Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	if e == nil {
+		e = ErrInternal
+	}
+	rr.write(w, r, e)
+}
+
+// write writes the error response negotiated with the client.
+func (rr *Renderer) write(w http.ResponseWriter, r *http.Request, e *Error) {
+	// The response depends on the negotiation, so caches must keep its variants apart
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "HX-Request")
+
+	if rr.opts.IsJSON(r) {
+		w.Header().Set("Content-Type", ContentTypeProblemJSON)
+		w.WriteHeader(e.Status)
+		if err := json.NewEncoder(w).Encode(e.Problem(r)); err != nil {
+			rr.logger().Warnw("Failed to write error response", "error", err)
+		}
+		return
+	}
+
+	page := rr.opts.Page
+	// htmx swaps the response into a part of the page, so the full page must not be sent.
+	// Boosted requests are regular navigations and get the full page.
+	if negotiate.IsHTMX(r) && !negotiate.IsHTMXBoosted(r) {
+		page = rr.opts.Fragment
+		w.Header().Set("HX-Retarget", rr.opts.ToastTarget)
+		w.Header().Set("HX-Reswap", "beforeend")
+	}
+
+	if page == nil {
+		http.Error(w, e.Message, e.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(e.Status)
+	if err := page(e.Status, e.Message).Render(r.Context(), w); err != nil {
+		rr.logger().Warnw("Failed to write error response", "error", err)
+	}
+}
+
+// log logs the unexpected error with its trace, the expected ones only at the debug level.
+func (rr *Renderer) log(r *http.Request, e *Error) {
+	fields := []interface{}{
+		"status", e.Status,
+		"code", e.Code,
+		"method", r.Method,
+		"path", r.URL.Path,
+	}
+	if e.Unexpected() {
+		cause := e.Unwrap()
+		if cause == nil {
+			cause = e
+		}
+		rr.logger().Errorw("Request failed", append(fields, "error", cause.Error(), "trace", errtrace.FormatString(cause))...)
+		return
+	}
+	rr.logger().Debugw("Request rejected", append(fields, "error", e.Error())...)
+}
+
+// logger returns the configured logger or the global one.
+func (rr *Renderer) logger() *zap.SugaredLogger {
+	if rr.opts.Logger != nil {
+		return rr.opts.Logger
+	}
+	return zap.S()
+}
+
+// defaultRenderer is the renderer used by Render.
+var defaultRenderer atomic.Pointer[Renderer]
+
+func init() {
+	defaultRenderer.Store(NewRenderer(Options{}))
+}
+
+// SetDefault replaces the renderer used by Render, call it on the app startup.
+func SetDefault(rr *Renderer) {
+	defaultRenderer.Store(rr)
+}
+
+// Render writes the error response with the default renderer, see SetDefault.
+func Render(w http.ResponseWriter, r *http.Request, err error) {
+	defaultRenderer.Load().Render(w, r, err)
+}
+
+// Write writes the error response with the default renderer without logging the error, see Renderer.Write.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	defaultRenderer.Load().Write(w, r, err)
+}
@@ -0,0 +1,159 @@
+// Package negotiate implements the content negotiation of the HTTP responses
+// based on the Accept header, and the detection of htmx requests.
+package negotiate
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Media types offered by the app.
+const (
+	MediaTypeHTML        = "text/html"
+	MediaTypeJSON        = "application/json"
+	MediaTypeProblemJSON = "application/problem+json"
+)
+
+// Accepts returns the offer preferred by the Accept header of the request,
+// or an empty string if none of the offers is acceptable.
+// Offers are ranked by the q-value of the most specific matching media range and then
+// by its specificity, so with "text/*;q=0.5, */*" both "text/html" and "application/json"
+// are acceptable, but the latter is preferred. Ties are resolved by the order of the offers.
+// A request without the Accept header accepts anything, so the first offer is returned.
+func Accepts(r *http.Request, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	header := r.Header.Values("Accept")
+	if len(header) == 0 {
+		return offers[0]
+	}
+	ranges := parseAccept(strings.Join(header, ","))
+
+	var best string
+	var bestPref preference
+	for _, offer := range offers {
+		if p := preferenceOf(ranges, offer); p.q > 0 && p.greater(bestPref) {
+			best, bestPref = offer, p
+		}
+	}
+	return best
+}
+
+// PrefersJSON reports whether the client prefers a JSON response to an HTML page.
+// The Content-Type of the request decides if the Accept header doesn't: it's missing
+// or ranks both the same, e.g. "*/*" sent by browsers in XHR requests and by API clients.
+// JSON excluded by the Accept header, e.g. "application/json;q=0", is never preferred.
+func PrefersJSON(r *http.Request) bool {
+	header := r.Header.Values("Accept")
+	if len(header) == 0 {
+		return hasJSONContentType(r)
+	}
+	ranges := parseAccept(strings.Join(header, ","))
+
+	html := preferenceOf(ranges, MediaTypeHTML)
+	json := preferenceOf(ranges, MediaTypeJSON)
+	if p := preferenceOf(ranges, MediaTypeProblemJSON); p.greater(json) {
+		json = p
+	}
+	if json.q == 0 {
+		return false
+	}
+	if json == html {
+		return hasJSONContentType(r)
+	}
+	return json.greater(html)
+}
+
+// IsHTMX reports whether the request is made by htmx.
+func IsHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// IsHTMXBoosted reports whether the request is made by an element with hx-boost,
+// such requests expect the whole page, as a regular navigation.
+func IsHTMXBoosted(r *http.Request) bool {
+	return r.Header.Get("HX-Boosted") == "true"
+}
+
+// mediaRange is a parsed element of the Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept parses the Accept header value, invalid elements are skipped.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// match returns the specificity of the range matching the media type: 3 for an exact match,
+// 2 for "type/*", 1 for "*/*", or 0 if the range doesn't match.
+func (m mediaRange) match(mediaType string) int {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	switch {
+	case m.typ == typ && m.subtype == subtype:
+		return 3
+	case m.typ == typ && m.subtype == "*":
+		return 2
+	case m.typ == "*":
+		return 1
+	}
+	return 0
+}
+
+// preference is the rank of a media type by the Accept header.
+type preference struct {
+	q           float64
+	specificity int
+}
+
+// greater reports whether the preference ranks higher than the other one.
+func (p preference) greater(other preference) bool {
+	return p.q > other.q || (p.q == other.q && p.specificity > other.specificity)
+}
+
+// preferenceOf returns the preference of the media type by its most specific matching range.
+// The media type excluded with q=0 gets the zero preference, whatever the specificity of the range,
+// as it's not acceptable at all.
+func preferenceOf(ranges []mediaRange, mediaType string) preference {
+	var p preference
+	for _, m := range ranges {
+		if s := m.match(mediaType); s > p.specificity {
+			p = preference{q: m.q, specificity: s}
+		}
+	}
+	if p.q == 0 {
+		return preference{}
+	}
+	return p
+}
+
+// hasJSONContentType reports whether the request body is JSON.
+func hasJSONContentType(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Content-Type")), "json")
+}
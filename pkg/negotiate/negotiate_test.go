@@ -0,0 +1,141 @@
+package negotiate
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAccept(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header string
+		want   []mediaRange
+	}{
+		{name: "empty", header: "", want: nil},
+		{
+			name:   "browser",
+			header: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			want: []mediaRange{
+				{typ: "text", subtype: "html", q: 1},
+				{typ: "application", subtype: "xhtml+xml", q: 1},
+				{typ: "application", subtype: "xml", q: 0.9},
+				{typ: "*", subtype: "*", q: 0.8},
+			},
+		},
+		{
+			name:   "spaces and case",
+			header: " Application/JSON ; Q=0.5 , text/*",
+			want: []mediaRange{
+				{typ: "application", subtype: "json", q: 0.5},
+				{typ: "text", subtype: "*", q: 1},
+			},
+		},
+		{
+			name:   "zero q",
+			header: "application/json;q=0",
+			want:   []mediaRange{{typ: "application", subtype: "json", q: 0}},
+		},
+		{
+			name:   "invalid elements are skipped",
+			header: "json, */html, text/, application/json;q=2, application/json;q=-1, application/json;q=x, ,text/html",
+			want:   []mediaRange{{typ: "text", subtype: "html", q: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parseAccept(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAccept(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	t.Parallel()
+
+	offers := []string{MediaTypeHTML, MediaTypeJSON}
+	tests := []struct {
+		name   string
+		accept []string
+		offers []string
+		want   string
+	}{
+		{name: "no header", accept: nil, offers: offers, want: MediaTypeHTML},
+		{name: "no offers", accept: []string{"*/*"}, offers: nil, want: ""},
+		{name: "any", accept: []string{"*/*"}, offers: offers, want: MediaTypeHTML},
+		{name: "exact", accept: []string{"application/json"}, offers: offers, want: MediaTypeJSON},
+		{name: "q-value", accept: []string{"text/html;q=0.5, application/json"}, offers: offers, want: MediaTypeJSON},
+		{name: "specificity", accept: []string{"text/*;q=0.5, */*"}, offers: offers, want: MediaTypeJSON},
+		{name: "more specific range wins", accept: []string{"*/*;q=0.1, application/json;q=0.9, text/*"}, offers: offers, want: MediaTypeHTML},
+		{name: "several headers", accept: []string{"text/html;q=0.1", "application/json"}, offers: offers, want: MediaTypeJSON},
+		{name: "not acceptable", accept: []string{"image/png"}, offers: offers, want: ""},
+		{name: "excluded", accept: []string{"application/json;q=0"}, offers: offers, want: ""},
+		{name: "excluded by specific range", accept: []string{"text/html;q=0, */*"}, offers: offers, want: MediaTypeJSON},
+		{name: "excluded by wildcard", accept: []string{"application/json, */*;q=0"}, offers: offers, want: MediaTypeJSON},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest("GET", "/", nil)
+			for _, v := range tt.accept {
+				r.Header.Add("Accept", v)
+			}
+			if got := Accepts(r, tt.offers...); got != tt.want {
+				t.Errorf("Accepts(%q, %q) = %q, want %q", tt.accept, tt.offers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefersJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		want        bool
+	}{
+		{name: "no header", want: false},
+		{name: "no header with json body", contentType: "application/json; charset=utf-8", want: true},
+		{name: "browser", accept: "text/html,application/xhtml+xml,*/*;q=0.8", contentType: "application/json", want: false},
+		{name: "json", accept: "application/json", want: true},
+		{name: "problem json", accept: "application/problem+json", want: true},
+		{name: "any", accept: "*/*", want: false},
+		{name: "any with json body", accept: "*/*", contentType: "application/json", want: true},
+		{name: "json preferred by q-value", accept: "text/html;q=0.5, application/json", want: true},
+		{name: "html preferred by q-value", accept: "text/html, application/json;q=0.5", contentType: "application/json", want: false},
+		{name: "json excluded", accept: "application/json;q=0", want: false},
+		{name: "json excluded with json body", accept: "application/json;q=0", contentType: "application/json", want: false},
+		{name: "json excluded, any other", accept: "application/json;q=0, */*", want: false},
+		{name: "html excluded", accept: "text/html;q=0, */*", want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			if got := PrefersJSON(r); got != tt.want {
+				t.Errorf("PrefersJSON(Accept: %q, Content-Type: %q) = %v, want %v", tt.accept, tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Package recovery provides the middleware recovering the panics of the HTTP handlers.
+package recovery
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/a-h/templ"
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+	"github.com/dmitrymomot/go-app-template/pkg/negotiate"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// DebugPageFunc returns the page with the panic details shown in debug mode, e.g. views.PanicPage.
+type DebugPageFunc func(r *http.Request, value any, stack []byte) templ.Component
+
+// Options defines the middleware settings.
+type Options struct {
+	// Logger logs the panics with the stack trace. Default: the global zap logger.
+	Logger *zap.SugaredLogger
+	// Debug shows DebugPage to HTML clients instead of the error page.
+	Debug     bool
+	DebugPage DebugPageFunc
+	// Panics counts the recovered panics, e.g. published with expvar.
+	Panics *expvar.Int
+}
+
+// Middleware recovers the panics of the next handlers, logs them with the stack trace
+// and the request details, and responds with httperr.ErrInternal.
+// The response is left as is if the handler has already started writing it.
+// http.ErrAbortHandler is propagated, as it's used to abort the response on purpose.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+
+				stack := debug.Stack()
+				if opts.Panics != nil {
+					opts.Panics.Add(1)
+				}
+
+				log := opts.Logger
+				if log == nil {
+					log = zap.S()
+				}
+				log.Errorw("Recovered from panic",
+					"panic", fmt.Sprint(v),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"query", r.URL.RawQuery,
+					"remote", r.RemoteAddr,
+					"user_agent", r.UserAgent(),
+					"request_id", middleware.GetReqID(r.Context()),
+					"stack", string(stack),
+				)
+
+				// The status and maybe a part of the body are sent already
+				if ww.Status() != 0 || r.Header.Get("Connection") == "Upgrade" {
+					return
+				}
+
+				if opts.Debug && opts.DebugPage != nil && !negotiate.PrefersJSON(r) && !negotiate.IsHTMX(r) {
+					w.Header().Set("Content-Type", "text/html; charset=utf-8")
+					w.WriteHeader(http.StatusInternalServerError)
+					if err := opts.DebugPage(r, v, stack).Render(r.Context(), w); err != nil {
+						log.Warnw("Failed to write panic page", "error", err)
+					}
+					return
+				}
+
+				// The panic is logged above with the stack, so the renderer must not log it again
+				httperr.Write(w, r, httperr.ErrInternal.WithCause(fmt.Errorf("panic: %v", v)))
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package recovery
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// The test replaces the default error renderer, so it doesn't run in parallel.
+func TestMiddlewareLogsOnce(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(core).Sugar()
+	httperr.SetDefault(httperr.NewRenderer(httperr.Options{Logger: log}))
+	t.Cleanup(func() { httperr.SetDefault(httperr.NewRenderer(httperr.Options{})) })
+
+	panics := new(expvar.Int)
+	h := Middleware(Options{Logger: log, Panics: panics})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != httperr.ContentTypeProblemJSON {
+		t.Errorf("Content-Type = %q, want %q", ct, httperr.ContentTypeProblemJSON)
+	}
+	if n := panics.Value(); n != 1 {
+		t.Errorf("panics = %d, want 1", n)
+	}
+	if entries := logs.All(); len(entries) != 1 || entries[0].Message != "Recovered from panic" {
+		t.Errorf("logged %d entries (%v), want only the recovered panic", len(entries), entries)
+	}
+}
+
+func TestMiddlewareAbortHandler(t *testing.T) {
+	t.Parallel()
+
+	h := Middleware(Options{Logger: zap.NewNop().Sugar()})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want %v", v, http.ErrAbortHandler)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,58 @@
+package render
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/pkg/negotiate"
+)
+
+// htmx response headers
+const (
+	HeaderHXRedirect = "HX-Redirect"
+	HeaderHXTrigger  = "HX-Trigger"
+	HeaderHXPushURL  = "HX-Push-Url"
+	HeaderHXRefresh  = "HX-Refresh"
+)
+
+// Redirect redirects the client to the url after a form submission (Post/Redirect/Get).
+// htmx follows redirects inside the XHR and swaps the response into the target, so htmx requests
+// get HX-Redirect with 200 OK instead, and htmx does the full page navigation.
+// Other requests get 303 See Other, which makes the browser load the url with GET.
+func Redirect(w http.ResponseWriter, r *http.Request, url string) {
+	if negotiate.IsHTMX(r) {
+		w.Header().Set(HeaderHXRedirect, url)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
+
+// Trigger makes htmx trigger the events on the client after the response is received.
+// It must be called before the response is written.
+func Trigger(w http.ResponseWriter, events ...string) {
+	w.Header().Set(HeaderHXTrigger, strings.Join(events, ", "))
+}
+
+// TriggerWithDetails makes htmx trigger the events with the details as event.detail on the client.
+// It replaces the events set by Trigger, both use the same header.
+func TriggerWithDetails(w http.ResponseWriter, events map[string]any) error {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	w.Header().Set(HeaderHXTrigger, string(b))
+	return nil
+}
+
+// PushURL makes htmx push the url into the browser history.
+func PushURL(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderHXPushURL, url)
+}
+
+// Refresh makes htmx do a full refresh of the page.
+func Refresh(w http.ResponseWriter) {
+	w.Header().Set(HeaderHXRefresh, "true")
+}
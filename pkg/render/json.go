@@ -0,0 +1,59 @@
+package render
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"braces.dev/errtrace"
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+)
+
+// JSON writes v as JSON with the status code.
+// Successful responses to GET and HEAD requests get the ETag of the body, and 304 Not Modified
+// is sent instead of the body if the If-None-Match header of the request matches it.
+func JSON(w http.ResponseWriter, r *http.Request, v any, status int) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		httperr.Render(w, r, errtrace.Wrap(err))
+		return
+	}
+
+	if status == http.StatusOK && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		etag := ETag(buf.Bytes())
+		w.Header().Set("ETag", etag)
+		if matchETag(r.Header.Values("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Set(ContentTypeHeader, ContentTypeJSONUTF)
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = buf.WriteTo(w)
+	}
+}
+
+// ETag returns the strong entity tag of the body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matchETag reports whether any of the If-None-Match header values matches the entity tag.
+// If-None-Match uses the weak comparison, so the W/ prefix is ignored.
+func matchETag(header []string, etag string) bool {
+	for _, value := range header {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+// Package render writes the HTTP responses: templ components, JSON, redirects and htmx headers.
+// Failures are rendered with httperr, so the client gets the same error response as from the handlers.
+package render
+
+import (
+	"net/http"
+
+	"braces.dev/errtrace"
+	"github.com/a-h/templ"
+	"github.com/dmitrymomot/go-app-template/pkg/httperr"
+	"github.com/dmitrymomot/go-app-template/pkg/negotiate"
+)
+
+// Predefined http encoder content type
+const (
+	ContentTypeHeader  = "Content-Type"
+	ContentTypeCharset = "charset=utf-8"
+	ContentTypeJSON    = negotiate.MediaTypeJSON
+	ContentTypeHTML    = negotiate.MediaTypeHTML
+	ContentTypeJSONUTF = ContentTypeJSON + "; " + ContentTypeCharset
+	ContentTypeHTMLUTF = ContentTypeHTML + "; " + ContentTypeCharset
+)
+
+// Render writes the templ component as HTML with the status code.
+// The component is rendered into a buffer first, so if it fails the client gets
+// the error page instead of a truncated one.
+func Render(w http.ResponseWriter, r *http.Request, c templ.Component, status int) {
+	buf := templ.GetBuffer()
+	defer templ.ReleaseBuffer(buf)
+
+	if err := c.Render(r.Context(), buf); err != nil {
+		httperr.Render(w, r, errtrace.Wrap(err))
+		return
+	}
+
+	w.Header().Set(ContentTypeHeader, ContentTypeHTMLUTF)
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = buf.WriteTo(w)
+	}
+}
+
+// Page writes the fragment to htmx requests, which swap a part of the page, and the full page to the rest.
+// Boosted requests are regular navigations and get the full page.
+func Page(w http.ResponseWriter, r *http.Request, page, fragment templ.Component, status int) {
+	w.Header().Add("Vary", "HX-Request")
+	if negotiate.IsHTMX(r) && !negotiate.IsHTMXBoosted(r) {
+		Render(w, r, fragment, status)
+		return
+	}
+	Render(w, r, page, status)
+}
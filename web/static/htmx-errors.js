@@ -0,0 +1,21 @@
+// htmx doesn't swap error responses by default.
+// Swap the ones retargeted by the server, e.g. the error messages sent to the toast area.
+document.addEventListener('htmx:beforeSwap', function (evt) {
+  if (evt.detail.isError && evt.detail.xhr.getResponseHeader('HX-Retarget')) {
+    evt.detail.shouldSwap = true
+    evt.detail.isError = false
+  }
+})
+
+// Hide the toasts after a few seconds.
+document.addEventListener('htmx:afterSwap', function (evt) {
+  if (evt.detail.target.id !== 'toasts') {
+    return
+  }
+  var toast = evt.detail.target.lastElementChild
+  setTimeout(function () {
+    if (toast) {
+      toast.remove()
+    }
+  }, 5000)
+})